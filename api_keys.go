@@ -3,8 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
-	"net/url"
-	"strconv"
+	"iter"
 )
 
 // APIKeysService handles API key operations.
@@ -13,7 +12,7 @@ type APIKeysService struct {
 }
 
 // Create creates a new API key.
-func (s *APIKeysService) Create(ctx context.Context, req CreateAPIKeyRequest) (*APIKeyWithSecret, *Error) {
+func (s *APIKeysService) Create(ctx context.Context, req CreateAPIKeyRequest) (*APIKeyWithSecret, error) {
 	body, err := s.http.Post(ctx, "/v1/api-keys", req, nil)
 	if err != nil {
 		return nil, err
@@ -21,14 +20,14 @@ func (s *APIKeysService) Create(ctx context.Context, req CreateAPIKeyRequest) (*
 
 	var resp APIKeyWithSecret
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Get retrieves an API key by ID.
-func (s *APIKeysService) Get(ctx context.Context, id string) (*APIKey, *Error) {
+func (s *APIKeysService) Get(ctx context.Context, id string) (*APIKey, error) {
 	body, err := s.http.Get(ctx, "/v1/api-keys/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -36,46 +35,24 @@ func (s *APIKeysService) Get(ctx context.Context, id string) (*APIKey, *Error) {
 
 	var resp APIKey
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // List lists all API keys.
-func (s *APIKeysService) List(ctx context.Context, opts *ListOptions) (*ListResponse[APIKey], *Error) {
-	path := "/v1/api-keys"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[APIKey]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *APIKeysService) List(ctx context.Context, opts *ListOptions) (*ListResponse[APIKey], error) {
+	return getList[APIKey](ctx, s.http, withQuery("/v1/api-keys", opts.values()))
+}
 
-	return &resp, nil
+// All iterates over all API keys, fetching pages as needed.
+func (s *APIKeysService) All(ctx context.Context, opts *ListOptions, iterOpts *IterOptions) iter.Seq2[APIKey, error] {
+	return paginate(ctx, opts, listPages[APIKey](s.http, "/v1/api-keys", opts.values()), iterOpts)
 }
 
 // Delete revokes an API key.
-func (s *APIKeysService) Delete(ctx context.Context, id string) *Error {
+func (s *APIKeysService) Delete(ctx context.Context, id string) error {
 	_, err := s.http.Delete(ctx, "/v1/api-keys/"+id, nil)
 	return err
 }
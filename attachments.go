@@ -0,0 +1,188 @@
+package sendpigeon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrAttachmentTooLarge is returned when an attachment exceeds a configured
+// size limit.
+var ErrAttachmentTooLarge = errors.New("sendpigeon: attachment too large")
+
+// AttachmentOptions configures AttachmentFromFile, AttachmentFromReader and
+// AttachmentFromFS.
+type AttachmentOptions struct {
+	// Filename overrides the attachment name. Defaults to the source's base name.
+	Filename string
+	// ContentType overrides MIME type detection.
+	ContentType string
+	// MaxSize rejects content larger than this many bytes. Zero means no limit.
+	MaxSize int64
+}
+
+// AttachmentFromFile reads a file into a base64-encoded Attachment.
+//
+// Example:
+//
+//	invoice, err := sendpigeon.AttachmentFromFile("invoices/2024-001.pdf", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	req.Attachments = append(req.Attachments, invoice)
+//	log.Println("attached", invoice.Filename, invoice.Checksum)
+func AttachmentFromFile(name string, opts *AttachmentOptions) (Attachment, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer f.Close()
+	return attachmentFromFile(f, filepath.Base(name), opts)
+}
+
+// AttachmentFromFS reads a file from fsys, such as an embed.FS, into a
+// base64-encoded Attachment.
+func AttachmentFromFS(fsys fs.FS, name string, opts *AttachmentOptions) (Attachment, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer f.Close()
+	return attachmentFromFile(f, path.Base(name), opts)
+}
+
+// attachmentFromFile checks the file size up front before streaming it.
+func attachmentFromFile(f fs.File, filename string, opts *AttachmentOptions) (Attachment, error) {
+	if opts != nil && opts.MaxSize > 0 {
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > opts.MaxSize {
+			return Attachment{}, fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrAttachmentTooLarge, filename, info.Size(), opts.MaxSize)
+		}
+	}
+	return AttachmentFromReader(filename, f, opts)
+}
+
+// AttachmentFromReader streams r into a base64-encoded Attachment named
+// filename. The content type is taken from opts, the file extension, or the
+// content itself, in that order. Size and a SHA-256 Checksum of the raw
+// content are recorded on the Attachment.
+func AttachmentFromReader(filename string, r io.Reader, opts *AttachmentOptions) (Attachment, error) {
+	var o AttachmentOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Filename != "" {
+		filename = o.Filename
+	}
+
+	// Keep the first bytes for content sniffing
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Attachment{}, err
+	}
+	head = head[:n]
+	src := io.MultiReader(bytes.NewReader(head), r)
+	if o.MaxSize > 0 {
+		src = io.LimitReader(src, o.MaxSize+1)
+	}
+
+	var encoded strings.Builder
+	hash := sha256.New()
+	enc := base64.NewEncoder(base64.StdEncoding, &encoded)
+	size, err := io.Copy(io.MultiWriter(enc, hash), src)
+	if err != nil {
+		return Attachment{}, err
+	}
+	enc.Close()
+
+	if o.MaxSize > 0 && size > o.MaxSize {
+		return Attachment{}, fmt.Errorf("%w: %s exceeds %d bytes", ErrAttachmentTooLarge, filename, o.MaxSize)
+	}
+
+	contentType := o.ContentType
+	if contentType == "" {
+		contentType = detectContentType(filename, head)
+	}
+
+	return Attachment{
+		Filename:    filename,
+		Content:     encoded.String(),
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// detectContentType guesses a MIME type from the file extension, falling
+// back to sniffing the content.
+func detectContentType(filename string, head []byte) string {
+	if ext := filepath.Ext(filename); ext != "" {
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+	}
+	return http.DetectContentType(head)
+}
+
+// decodedSize returns the attachment's size in bytes, computing it from the
+// base64 content when Size was not recorded. Remote attachments report zero.
+func (a Attachment) decodedSize() int64 {
+	if a.Size > 0 {
+		return a.Size
+	}
+	// Count only alphabet characters, so line breaks and padding in wrapped
+	// base64 do not inflate the size
+	var n int64
+	for i := 0; i < len(a.Content); i++ {
+		switch c := a.Content[i]; {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '+', c == '/', c == '-', c == '_':
+			n++
+		}
+	}
+	return n * 3 / 4
+}
+
+// checkAttachmentLimits enforces the client's attachment size limits.
+func (c *httpClient) checkAttachmentLimits(prefix string, attachments []Attachment) error {
+	if c.maxAttachmentSize <= 0 && c.maxTotalAttachmentSize <= 0 {
+		return nil
+	}
+
+	var errs fieldErrors
+	var total int64
+	for i, a := range attachments {
+		size := a.decodedSize()
+		total += size
+		if c.maxAttachmentSize > 0 && size > c.maxAttachmentSize {
+			errs.add(fmt.Sprintf("%sattachments[%d]", prefix, i), "max_size",
+				fmt.Sprintf("%s is %d bytes, limit is %d", a.Filename, size, c.maxAttachmentSize))
+		}
+	}
+	if c.maxTotalAttachmentSize > 0 && total > c.maxTotalAttachmentSize {
+		errs.add(prefix+"attachments", "max_size",
+			fmt.Sprintf("attachments total %d bytes, limit is %d", total, c.maxTotalAttachmentSize))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	ve := &ValidationError{Fields: errs}
+	return &Error{
+		Code:       ErrorCodeValidation,
+		Message:    ve.Error(),
+		Validation: ve,
+		cause:      ErrAttachmentTooLarge,
+	}
+}
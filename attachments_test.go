@@ -0,0 +1,109 @@
+package sendpigeon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAttachmentFromFS(t *testing.T) {
+	pdf := "%PDF-1.4 fake invoice"
+	fsys := fstest.MapFS{
+		"docs/invoice.pdf": {Data: []byte(pdf)},
+		"docs/blob":        {Data: []byte("\x89PNG\r\n\x1a\nrest")},
+	}
+
+	a, err := AttachmentFromFS(fsys, "docs/invoice.pdf", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Filename != "invoice.pdf" {
+		t.Errorf("expected filename invoice.pdf, got %s", a.Filename)
+	}
+	if a.ContentType != "application/pdf" {
+		t.Errorf("expected application/pdf, got %s", a.ContentType)
+	}
+	if a.Content != base64.StdEncoding.EncodeToString([]byte(pdf)) {
+		t.Errorf("unexpected content %s", a.Content)
+	}
+	sum := sha256.Sum256([]byte(pdf))
+	if a.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected checksum %s", a.Checksum)
+	}
+	if a.Size != int64(len(pdf)) {
+		t.Errorf("expected size %d, got %d", len(pdf), a.Size)
+	}
+
+	// No extension: sniffed from content
+	a, err = AttachmentFromFS(fsys, "docs/blob", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.ContentType != "image/png" {
+		t.Errorf("expected image/png, got %s", a.ContentType)
+	}
+
+	_, err = AttachmentFromFS(fsys, "docs/invoice.pdf", &AttachmentOptions{MaxSize: 5})
+	if !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+}
+
+func TestAttachmentFromReaderLimit(t *testing.T) {
+	_, err := AttachmentFromReader("big.txt", strings.NewReader(strings.Repeat("x", 2000)), &AttachmentOptions{MaxSize: 1000})
+	if !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+
+	a, err := AttachmentFromReader("data", strings.NewReader("hello"), &AttachmentOptions{Filename: "hello.txt", ContentType: "text/x-custom"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Filename != "hello.txt" || a.ContentType != "text/x-custom" {
+		t.Errorf("expected overrides to apply, got %+v", a)
+	}
+}
+
+func TestSendEnforcesAttachmentLimits(t *testing.T) {
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:                "http://127.0.0.1:0",
+		MaxTotalAttachmentSize: 10,
+	})
+	_, err := client.Send(context.Background(), SendEmailRequest{
+		To:      []string{"user@example.com"},
+		Subject: "Files",
+		Text:    "See attached",
+		Attachments: []Attachment{
+			{Filename: "a.txt", Content: base64.StdEncoding.EncodeToString([]byte("123456"))},
+			{Filename: "b.txt", Content: base64.StdEncoding.EncodeToString([]byte("7890123"))},
+		},
+	})
+	if !errors.Is(err, ErrAttachmentTooLarge) || !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrAttachmentTooLarge validation error, got %v", err)
+	}
+}
+
+func TestAttachmentDecodedSize(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 100, 1000} {
+		data := strings.Repeat("x", n)
+		encoded := base64.StdEncoding.EncodeToString([]byte(data))
+
+		// Wrap at 76 characters with CRLF, as MIME encoders do
+		var wrapped strings.Builder
+		for i := 0; i < len(encoded); i += 76 {
+			wrapped.WriteString(encoded[i:min(i+76, len(encoded))])
+			wrapped.WriteString("\r\n")
+		}
+
+		for _, content := range []string{encoded, wrapped.String(), strings.TrimRight(encoded, "=")} {
+			if got := (Attachment{Content: content}).decodedSize(); got != int64(n) {
+				t.Errorf("decodedSize of %d bytes (%d chars) = %d", n, len(content), got)
+			}
+		}
+	}
+}
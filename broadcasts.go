@@ -3,8 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
-	"net/url"
-	"strconv"
+	"iter"
 )
 
 // BroadcastsService handles broadcast operations.
@@ -13,42 +12,21 @@ type BroadcastsService struct {
 }
 
 // List lists broadcasts with optional filtering.
-func (s *BroadcastsService) List(ctx context.Context, opts *ListBroadcastsOptions) (*ListResponse[Broadcast], *Error) {
-	path := "/v1/broadcasts"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if opts.Status != "" {
-			params.Set("status", opts.Status)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[Broadcast]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *BroadcastsService) List(ctx context.Context, opts *ListBroadcastsOptions) (*ListResponse[Broadcast], error) {
+	return getList[Broadcast](ctx, s.http, withQuery("/v1/broadcasts", opts.values()))
+}
 
-	return &resp, nil
+// All iterates over all broadcasts matching opts, fetching pages as needed.
+func (s *BroadcastsService) All(ctx context.Context, opts *ListBroadcastsOptions, iterOpts *IterOptions) iter.Seq2[Broadcast, error] {
+	return paginate(ctx, opts.listOptions(), listPages[Broadcast](s.http, "/v1/broadcasts", opts.values()), iterOpts)
 }
 
 // Create creates a new broadcast.
-func (s *BroadcastsService) Create(ctx context.Context, req CreateBroadcastRequest) (*Broadcast, *Error) {
+func (s *BroadcastsService) Create(ctx context.Context, req CreateBroadcastRequest) (*Broadcast, error) {
+	if err := s.http.validate(req.Validate); err != nil {
+		return nil, err
+	}
+
 	body, err := s.http.Post(ctx, "/v1/broadcasts", req, nil)
 	if err != nil {
 		return nil, err
@@ -56,14 +34,14 @@ func (s *BroadcastsService) Create(ctx context.Context, req CreateBroadcastReque
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Get retrieves a broadcast by ID.
-func (s *BroadcastsService) Get(ctx context.Context, id string) (*Broadcast, *Error) {
+func (s *BroadcastsService) Get(ctx context.Context, id string) (*Broadcast, error) {
 	body, err := s.http.Get(ctx, "/v1/broadcasts/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -71,14 +49,14 @@ func (s *BroadcastsService) Get(ctx context.Context, id string) (*Broadcast, *Er
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Update updates a broadcast.
-func (s *BroadcastsService) Update(ctx context.Context, id string, req UpdateBroadcastRequest) (*Broadcast, *Error) {
+func (s *BroadcastsService) Update(ctx context.Context, id string, req UpdateBroadcastRequest) (*Broadcast, error) {
 	body, err := s.http.Patch(ctx, "/v1/broadcasts/"+id, req, nil)
 	if err != nil {
 		return nil, err
@@ -86,20 +64,20 @@ func (s *BroadcastsService) Update(ctx context.Context, id string, req UpdateBro
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Delete removes a broadcast.
-func (s *BroadcastsService) Delete(ctx context.Context, id string) *Error {
+func (s *BroadcastsService) Delete(ctx context.Context, id string) error {
 	_, err := s.http.Delete(ctx, "/v1/broadcasts/"+id, nil)
 	return err
 }
 
 // Duplicate creates a copy of a broadcast.
-func (s *BroadcastsService) Duplicate(ctx context.Context, id string) (*Broadcast, *Error) {
+func (s *BroadcastsService) Duplicate(ctx context.Context, id string) (*Broadcast, error) {
 	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/duplicate", nil, nil)
 	if err != nil {
 		return nil, err
@@ -107,83 +85,64 @@ func (s *BroadcastsService) Duplicate(ctx context.Context, id string) (*Broadcas
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Recipients lists recipients of a broadcast.
-func (s *BroadcastsService) Recipients(ctx context.Context, id string, opts *ListRecipientsOptions) (*ListResponse[BroadcastRecipient], *Error) {
-	path := "/v1/broadcasts/" + id + "/recipients"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if opts.Status != "" {
-			params.Set("status", opts.Status)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[BroadcastRecipient]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *BroadcastsService) Recipients(ctx context.Context, id string, opts *ListRecipientsOptions) (*ListResponse[BroadcastRecipient], error) {
+	return getList[BroadcastRecipient](ctx, s.http, withQuery("/v1/broadcasts/"+id+"/recipients", opts.values()))
+}
 
-	return &resp, nil
+// AllRecipients iterates over all recipients of a broadcast, fetching pages as needed.
+func (s *BroadcastsService) AllRecipients(ctx context.Context, id string, opts *ListRecipientsOptions, iterOpts *IterOptions) iter.Seq2[BroadcastRecipient, error] {
+	return paginate(ctx, opts.listOptions(), listPages[BroadcastRecipient](s.http, "/v1/broadcasts/"+id+"/recipients", opts.values()), iterOpts)
 }
 
 // Send sends a broadcast immediately with optional targeting.
-func (s *BroadcastsService) Send(ctx context.Context, id string, req *SendBroadcastRequest) (*Broadcast, *Error) {
+func (s *BroadcastsService) Send(ctx context.Context, id string, req *SendBroadcastRequest) (*Broadcast, error) {
 	var reqBody interface{}
 	if req != nil {
 		reqBody = req
 	}
-	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/send", reqBody, nil)
+	headers := s.http.idempotencyHeaders("")
+
+	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/send", reqBody, headers)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
+	resp.IdempotencyKey = headers[idempotencyHeader]
 
 	return &resp, nil
 }
 
 // Schedule schedules a broadcast for later.
-func (s *BroadcastsService) Schedule(ctx context.Context, id string, req ScheduleBroadcastRequest) (*Broadcast, *Error) {
-	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/schedule", req, nil)
+func (s *BroadcastsService) Schedule(ctx context.Context, id string, req ScheduleBroadcastRequest) (*Broadcast, error) {
+	headers := s.http.idempotencyHeaders("")
+
+	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/schedule", req, headers)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
+	resp.IdempotencyKey = headers[idempotencyHeader]
 
 	return &resp, nil
 }
 
 // Cancel cancels a scheduled broadcast.
-func (s *BroadcastsService) Cancel(ctx context.Context, id string) (*Broadcast, *Error) {
+func (s *BroadcastsService) Cancel(ctx context.Context, id string) (*Broadcast, error) {
 	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/cancel", nil, nil)
 	if err != nil {
 		return nil, err
@@ -191,14 +150,14 @@ func (s *BroadcastsService) Cancel(ctx context.Context, id string) (*Broadcast,
 
 	var resp Broadcast
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Test sends a test email for a broadcast.
-func (s *BroadcastsService) Test(ctx context.Context, id string, req TestBroadcastRequest) (*TestBroadcastResponse, *Error) {
+func (s *BroadcastsService) Test(ctx context.Context, id string, req TestBroadcastRequest) (*TestBroadcastResponse, error) {
 	body, err := s.http.Post(ctx, "/v1/broadcasts/"+id+"/test", req, nil)
 	if err != nil {
 		return nil, err
@@ -206,14 +165,14 @@ func (s *BroadcastsService) Test(ctx context.Context, id string, req TestBroadca
 
 	var resp TestBroadcastResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Analytics retrieves detailed analytics for a broadcast.
-func (s *BroadcastsService) Analytics(ctx context.Context, id string) (*BroadcastAnalytics, *Error) {
+func (s *BroadcastsService) Analytics(ctx context.Context, id string) (*BroadcastAnalytics, error) {
 	body, err := s.http.Get(ctx, "/v1/broadcasts/"+id+"/analytics", nil)
 	if err != nil {
 		return nil, err
@@ -221,7 +180,7 @@ func (s *BroadcastsService) Analytics(ctx context.Context, id string) (*Broadcas
 
 	var resp BroadcastAnalytics
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
@@ -0,0 +1,217 @@
+package sendpigeon
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// MessageBuilder builds a SendEmailRequest from structured addresses.
+// Display names are quoted or RFC 2047 encoded as needed, internationalized
+// domains are converted to punycode, and recipients repeated across To, CC
+// and BCC are dropped from the later lists.
+//
+// Example:
+//
+//	req, err := sendpigeon.NewMessage().
+//	    From(mail.Address{Name: "Acme Support", Address: "support@acme.com"}).
+//	    To(mail.Address{Name: "Doe, Jane", Address: "jane@example.com"}).
+//	    ReplyTo(mail.Address{Address: "help@acme.com"}, mail.Address{Address: "billing@acme.com"}).
+//	    Subject("Your receipt").
+//	    HTML("<p>Thanks for your order!</p>").
+//	    Build()
+type MessageBuilder struct {
+	req     SendEmailRequest
+	from    *mail.Address
+	to      []mail.Address
+	cc      []mail.Address
+	bcc     []mail.Address
+	replyTo []mail.Address
+}
+
+// NewMessage returns an empty MessageBuilder.
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// From sets the sender.
+func (b *MessageBuilder) From(addr mail.Address) *MessageBuilder {
+	b.from = &addr
+	return b
+}
+
+// To adds primary recipients.
+func (b *MessageBuilder) To(addrs ...mail.Address) *MessageBuilder {
+	b.to = append(b.to, addrs...)
+	return b
+}
+
+// CC adds carbon-copy recipients.
+func (b *MessageBuilder) CC(addrs ...mail.Address) *MessageBuilder {
+	b.cc = append(b.cc, addrs...)
+	return b
+}
+
+// BCC adds blind carbon-copy recipients.
+func (b *MessageBuilder) BCC(addrs ...mail.Address) *MessageBuilder {
+	b.bcc = append(b.bcc, addrs...)
+	return b
+}
+
+// ReplyTo adds Reply-To addresses.
+func (b *MessageBuilder) ReplyTo(addrs ...mail.Address) *MessageBuilder {
+	b.replyTo = append(b.replyTo, addrs...)
+	return b
+}
+
+// Subject sets the subject line.
+func (b *MessageBuilder) Subject(subject string) *MessageBuilder {
+	b.req.Subject = subject
+	return b
+}
+
+// HTML sets the HTML body.
+func (b *MessageBuilder) HTML(html string) *MessageBuilder {
+	b.req.HTML = html
+	return b
+}
+
+// Text sets the plain-text body.
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.req.Text = text
+	return b
+}
+
+// Template sends using a stored template with the given variables.
+func (b *MessageBuilder) Template(templateID string, variables map[string]string) *MessageBuilder {
+	b.req.TemplateID = templateID
+	b.req.Variables = maps.Clone(variables)
+	return b
+}
+
+// Attach adds attachments.
+func (b *MessageBuilder) Attach(attachments ...Attachment) *MessageBuilder {
+	b.req.Attachments = append(b.req.Attachments, attachments...)
+	return b
+}
+
+// Tags adds tags.
+func (b *MessageBuilder) Tags(tags ...string) *MessageBuilder {
+	b.req.Tags = append(b.req.Tags, tags...)
+	return b
+}
+
+// Metadata sets a metadata key.
+func (b *MessageBuilder) Metadata(key, value string) *MessageBuilder {
+	if b.req.Metadata == nil {
+		b.req.Metadata = make(map[string]string)
+	}
+	b.req.Metadata[key] = value
+	return b
+}
+
+// Header sets a custom header.
+func (b *MessageBuilder) Header(key, value string) *MessageBuilder {
+	if b.req.Headers == nil {
+		b.req.Headers = make(map[string]string)
+	}
+	b.req.Headers[key] = value
+	return b
+}
+
+// Tracking sets per-email tracking options.
+func (b *MessageBuilder) Tracking(opts *TrackingOptions) *MessageBuilder {
+	b.req.Tracking = opts
+	return b
+}
+
+// ScheduleAt schedules the email for delivery at t.
+func (b *MessageBuilder) ScheduleAt(t time.Time) *MessageBuilder {
+	b.req.ScheduleAt(t)
+	return b
+}
+
+// IdempotencyKey sets the idempotency key.
+func (b *MessageBuilder) IdempotencyKey(key string) *MessageBuilder {
+	b.req.IdempotencyKey = key
+	return b
+}
+
+// Build formats the addresses and returns the validated request. The error,
+// if any, is a *ValidationError.
+func (b *MessageBuilder) Build() (SendEmailRequest, error) {
+	req := b.req
+	req.Attachments = append([]Attachment(nil), b.req.Attachments...)
+	req.Tags = append([]string(nil), b.req.Tags...)
+	req.Metadata = maps.Clone(b.req.Metadata)
+	req.Headers = maps.Clone(b.req.Headers)
+
+	var errs fieldErrors
+	seen := make(map[string]bool)
+
+	if b.from != nil {
+		if s, _, err := formatAddress(*b.from); err != nil {
+			errs.add("from", "email", err.Error())
+		} else {
+			req.From = s
+		}
+	}
+	req.To = formatRecipients("to", b.to, seen, &errs)
+	req.CC = formatRecipients("cc", b.cc, seen, &errs)
+	req.BCC = formatRecipients("bcc", b.bcc, seen, &errs)
+
+	replyTo := formatRecipients("replyTo", b.replyTo, make(map[string]bool), &errs)
+	req.ReplyTo = strings.Join(replyTo, ", ")
+
+	if len(errs) > 0 {
+		return req, errs.err()
+	}
+	if err := req.Validate(); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// formatRecipients formats addrs, skipping any already in seen.
+func formatRecipients(field string, addrs []mail.Address, seen map[string]bool, errs *fieldErrors) []string {
+	var out []string
+	for i, addr := range addrs {
+		s, key, err := formatAddress(addr)
+		if err != nil {
+			errs.add(fmt.Sprintf("%s[%d]", field, i), "email", err.Error())
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// formatAddress renders addr per RFC 5322 with an ASCII domain. It also
+// returns a case-insensitive key for de-duplication.
+func formatAddress(addr mail.Address) (formatted, key string, err error) {
+	at := strings.LastIndex(addr.Address, "@")
+	if at <= 0 || at == len(addr.Address)-1 {
+		return "", "", fmt.Errorf("invalid email address %q", addr.Address)
+	}
+	local := addr.Address[:at]
+	domain, err := toASCIIDomain(addr.Address[at+1:])
+	if err != nil {
+		return "", "", err
+	}
+
+	a := mail.Address{Name: addr.Name, Address: local + "@" + domain}
+	formatted = a.String()
+	if a.Name == "" {
+		formatted = strings.TrimSuffix(strings.TrimPrefix(formatted, "<"), ">")
+	}
+	if _, err := mail.ParseAddress(formatted); err != nil {
+		return "", "", fmt.Errorf("invalid email address %q", addr.Address)
+	}
+	return formatted, strings.ToLower(a.Address), nil
+}
@@ -0,0 +1,76 @@
+package sendpigeon
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"testing"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	req, err := NewMessage().
+		From(mail.Address{Name: "Acme Support", Address: "support@acme.com"}).
+		To(mail.Address{Name: "Doe, Jane", Address: "jane@example.com"}, mail.Address{Address: "max@bücher.example"}).
+		CC(mail.Address{Address: "JANE@example.com"}, mail.Address{Address: "ops@acme.com"}).
+		BCC(mail.Address{Address: "ops@acme.com"}, mail.Address{Address: "audit@acme.com"}).
+		ReplyTo(mail.Address{Address: "help@acme.com"}, mail.Address{Name: "Billing", Address: "billing@acme.com"}).
+		Subject("Your receipt").
+		HTML("<p>Thanks!</p>").
+		Metadata("order", "123").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.From != `"Acme Support" <support@acme.com>` {
+		t.Errorf("unexpected From: %s", req.From)
+	}
+	if fmt.Sprint(req.To) != `["Doe, Jane" <jane@example.com> max@xn--bcher-kva.example]` {
+		t.Errorf("unexpected To: %q", req.To)
+	}
+	if fmt.Sprint(req.CC) != `[ops@acme.com]` {
+		t.Errorf("expected duplicate of To to be dropped from CC, got %q", req.CC)
+	}
+	if fmt.Sprint(req.BCC) != `[audit@acme.com]` {
+		t.Errorf("expected duplicate of CC to be dropped from BCC, got %q", req.BCC)
+	}
+	if req.ReplyTo != `help@acme.com, "Billing" <billing@acme.com>` {
+		t.Errorf("unexpected ReplyTo: %s", req.ReplyTo)
+	}
+	if req.Metadata["order"] != "123" {
+		t.Errorf("unexpected metadata: %v", req.Metadata)
+	}
+}
+
+func TestMessageBuilderValidates(t *testing.T) {
+	_, err := NewMessage().
+		To(mail.Address{Address: "not-an-address"}).
+		Subject("Hi").
+		Build()
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if ve.Fields[0].Field != "to[0]" {
+		t.Errorf("unexpected field error: %+v", ve.Fields[0])
+	}
+}
+
+func TestToASCIIDomain(t *testing.T) {
+	tests := map[string]string{
+		"example.com":    "example.com",
+		"Bücher.example": "xn--bcher-kva.example",
+		"münchen.de":     "xn--mnchen-3ya.de",
+		"例え.テスト":         "xn--r8jz45g.xn--zckzah",
+	}
+	for in, want := range tests {
+		got, err := toASCIIDomain(in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", in, err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+}
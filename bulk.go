@@ -0,0 +1,305 @@
+package sendpigeon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	defaultBulkConcurrency = 4
+	defaultBulkMaxRetries  = 2
+)
+
+// defaultRetryableBatchCodes are the per-email batch error codes that are
+// worth sending again.
+var defaultRetryableBatchCodes = []string{"rate_limited", "internal_error", "service_unavailable", "timeout"}
+
+// BulkOptions configures a BulkSender.
+type BulkOptions struct {
+	// BatchSize is the number of emails per SendBatch call. Defaults to,
+	// and is capped at, MaxBatchSize.
+	BatchSize int
+	// Concurrency is the number of batches in flight at once. Defaults to 4.
+	Concurrency int
+	// MaxRetries is how many times emails that failed with a retryable
+	// error are sent again. Zero means the default of 2; a negative value
+	// disables retries.
+	MaxRetries int
+	// RetryableCodes are the batch error codes that are retried. Defaults
+	// to rate_limited, internal_error, service_unavailable and timeout.
+	RetryableCodes []string
+}
+
+// BulkSender sends any number of emails by splitting them into API-sized
+// batches and dispatching them concurrently. Emails that fail client-side
+// validation are reported without being sent, and emails the API rejects
+// with a retryable error are sent again on their own.
+//
+// Example:
+//
+//	bulk := sendpigeon.NewBulkSender(client, &sendpigeon.BulkOptions{Concurrency: 8})
+//	result, err := bulk.Send(ctx, emails)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, r := range result.Results {
+//	    if r.Err != nil {
+//	        log.Printf("email %d failed: %v", r.Index, r.Err)
+//	    }
+//	}
+type BulkSender struct {
+	client         *Client
+	batchSize      int
+	concurrency    int
+	maxRetries     int
+	retryableCodes []string
+}
+
+// BulkEmailResult is the outcome for one email passed to a BulkSender.
+type BulkEmailResult struct {
+	// Index is the email's position in the input.
+	Index      int
+	ID         string
+	Suppressed []string
+	Warnings   []string
+	// Err is set if the email was not sent. It is an *Error.
+	Err error
+	// Attempts is the number of times the email was submitted.
+	Attempts int
+}
+
+// BulkResult aggregates the outcome of a bulk send.
+type BulkResult struct {
+	// Results holds one entry per input email, in input order.
+	Results []BulkEmailResult
+	Sent    int
+	Failed  int
+}
+
+// bulkEntry is an email waiting to be sent, with its input position.
+type bulkEntry struct {
+	index int
+	req   SendEmailRequest
+}
+
+// NewBulkSender returns a BulkSender that sends through client.
+func NewBulkSender(client *Client, opts *BulkOptions) *BulkSender {
+	b := &BulkSender{
+		client:         client,
+		batchSize:      MaxBatchSize,
+		concurrency:    defaultBulkConcurrency,
+		maxRetries:     defaultBulkMaxRetries,
+		retryableCodes: defaultRetryableBatchCodes,
+	}
+	if opts != nil {
+		if opts.BatchSize > 0 && opts.BatchSize < MaxBatchSize {
+			b.batchSize = opts.BatchSize
+		}
+		if opts.Concurrency > 0 {
+			b.concurrency = opts.Concurrency
+		}
+		if opts.MaxRetries < 0 {
+			b.maxRetries = 0
+		} else if opts.MaxRetries > 0 {
+			b.maxRetries = opts.MaxRetries
+		}
+		if opts.RetryableCodes != nil {
+			b.retryableCodes = opts.RetryableCodes
+		}
+	}
+	return b
+}
+
+// Send sends emails. Per-email failures are reported in the result; the
+// returned error is only set if ctx ends first, in which case the result
+// covers the emails that were processed.
+func (b *BulkSender) Send(ctx context.Context, emails []SendEmailRequest) (*BulkResult, error) {
+	ch := make(chan SendEmailRequest)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		defer close(ch)
+		for _, email := range emails {
+			select {
+			case ch <- email:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return b.SendChan(ctx, ch)
+}
+
+// SendChan sends every email received from emails until it is closed.
+// Batches are dispatched as soon as they fill up, so producers can stream
+// emails without holding them all in memory. Result indexes follow the
+// order in which emails were received.
+func (b *BulkSender) SendChan(ctx context.Context, emails <-chan SendEmailRequest) (*BulkResult, error) {
+	var (
+		mu      sync.Mutex
+		results = make(map[int]BulkEmailResult)
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, b.concurrency)
+	)
+	record := func(r BulkEmailResult) {
+		mu.Lock()
+		results[r.Index] = r
+		mu.Unlock()
+	}
+
+	var chunk []bulkEntry
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		entries := chunk
+		chunk = nil
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for _, e := range entries {
+				record(BulkEmailResult{Index: e.index, Err: contextError(ctx.Err())})
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.dispatch(ctx, entries, record)
+		}()
+	}
+
+	n := 0
+read:
+	for {
+		select {
+		case req, ok := <-emails:
+			if !ok {
+				break read
+			}
+			entry := bulkEntry{index: n, req: req}
+			n++
+			if err := b.check(req); err != nil {
+				record(BulkEmailResult{Index: entry.index, Err: err})
+				continue
+			}
+			chunk = append(chunk, entry)
+			if len(chunk) >= b.batchSize {
+				flush()
+			}
+		case <-ctx.Done():
+			break read
+		}
+	}
+	flush()
+	wg.Wait()
+
+	result := &BulkResult{Results: make([]BulkEmailResult, n)}
+	for i := range result.Results {
+		r := results[i]
+		r.Index = i
+		result.Results[i] = r
+		if r.Err != nil {
+			result.Failed++
+		} else {
+			result.Sent++
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return result, contextError(err)
+	}
+	return result, nil
+}
+
+// check runs the client-side checks SendBatch would otherwise apply to the
+// whole batch, so one bad email cannot fail its neighbours.
+func (b *BulkSender) check(req SendEmailRequest) error {
+	if err := b.client.http.validate(req.Validate); err != nil {
+		return err
+	}
+	return b.client.http.checkAttachmentLimits("", req.Attachments)
+}
+
+// dispatch sends one batch, resending retryable failures until they
+// succeed or the retry budget is spent.
+func (b *BulkSender) dispatch(ctx context.Context, pending []bulkEntry, record func(BulkEmailResult)) {
+	for attempt := 0; ; attempt++ {
+		reqs := make([]SendEmailRequest, len(pending))
+		for i, e := range pending {
+			reqs[i] = e.req
+		}
+
+		resp, err := b.client.SendBatch(ctx, reqs)
+		if err != nil {
+			for _, e := range pending {
+				record(BulkEmailResult{Index: e.index, Err: err, Attempts: attempt + 1})
+			}
+			return
+		}
+
+		byIndex := make(map[int]BatchEmailResult, len(resp.Data))
+		for _, r := range resp.Data {
+			byIndex[r.Index] = r
+		}
+
+		var retry []bulkEntry
+		for i, e := range pending {
+			res := BulkEmailResult{Index: e.index, Attempts: attempt + 1}
+			r, ok := byIndex[i]
+			switch {
+			case !ok:
+				res.Err = NewError(ErrorCodeAPI, "no result returned for email")
+			case r.Failed():
+				res.Err = batchEntryError(r)
+				res.Warnings = r.Warnings
+				if attempt < b.maxRetries && b.retryable(r) {
+					retry = append(retry, e)
+					continue
+				}
+			default:
+				res.ID = r.ID
+				res.Suppressed = r.Suppressed
+				res.Warnings = r.Warnings
+			}
+			record(res)
+		}
+
+		if len(retry) == 0 {
+			return
+		}
+		if err := sleepContext(ctx, b.client.http.retry.Backoff(attempt, 0)); err != nil {
+			for _, e := range retry {
+				record(BulkEmailResult{Index: e.index, Err: contextError(err), Attempts: attempt + 1})
+			}
+			return
+		}
+		pending = retry
+	}
+}
+
+// retryable reports whether a failed batch entry should be sent again.
+func (b *BulkSender) retryable(r BatchEmailResult) bool {
+	if r.Error == nil {
+		return false
+	}
+	for _, c := range b.retryableCodes {
+		if r.Error.Code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// batchEntryError converts a failed batch entry into an *Error that
+// unwraps to its *BatchError.
+func batchEntryError(r BatchEmailResult) *Error {
+	if r.Error == nil {
+		return NewError(ErrorCodeAPI, fmt.Sprintf("email %d failed", r.Index))
+	}
+	err := wrapError(ErrorCodeAPI, r.Error.Error(), r.Error)
+	err.APICode = r.Error.Code
+	return err
+}
@@ -0,0 +1,132 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkSender(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+		inFlight atomic.Int32
+		peak     atomic.Int32
+		batches  atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		batches.Add(1)
+		time.Sleep(5 * time.Millisecond)
+
+		var body struct {
+			Emails []SendEmailRequest `json:"emails"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Emails) > MaxBatchSize {
+			t.Errorf("batch of %d exceeds MaxBatchSize", len(body.Emails))
+		}
+
+		var data []map[string]interface{}
+		for i, email := range body.Emails {
+			mu.Lock()
+			attempts[email.Subject]++
+			attempt := attempts[email.Subject]
+			mu.Unlock()
+
+			switch {
+			case email.Subject == "7" && attempt == 1:
+				data = append(data, map[string]interface{}{"index": i, "status": "failed",
+					"error": map[string]interface{}{"code": "rate_limited", "message": "slow down"}})
+			case email.Subject == "42":
+				data = append(data, map[string]interface{}{"index": i, "status": "failed",
+					"error": map[string]interface{}{"code": "invalid_recipient", "message": "mailbox does not exist"}})
+			default:
+				data = append(data, map[string]interface{}{"index": i, "status": "sent", "id": "email_" + email.Subject})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:     server.URL,
+		RetryPolicy: &DefaultRetryPolicy{BaseDelay: time.Millisecond, DisableJitter: true},
+	})
+
+	emails := make([]SendEmailRequest, 250)
+	for i := range emails {
+		emails[i] = SendEmailRequest{To: []string{"user@example.com"}, Subject: fmt.Sprint(i), HTML: "<p>Hi</p>"}
+	}
+	emails[100].To = nil // fails validation
+
+	bulk := NewBulkSender(client, &BulkOptions{Concurrency: 2, MaxRetries: 2})
+	result, err := bulk.Send(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Results) != len(emails) {
+		t.Fatalf("expected %d results, got %d", len(emails), len(result.Results))
+	}
+	if result.Sent != 248 || result.Failed != 2 {
+		t.Errorf("expected 248 sent and 2 failed, got %d and %d", result.Sent, result.Failed)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("expected at most 2 concurrent batches, saw %d", got)
+	}
+	if got := batches.Load(); got != 4 {
+		t.Errorf("expected 3 batches plus 1 retry, got %d", got)
+	}
+
+	for i, r := range result.Results {
+		if r.Index != i {
+			t.Errorf("result %d has index %d", i, r.Index)
+		}
+	}
+	if r := result.Results[7]; r.Err != nil || r.ID != "email_7" || r.Attempts != 2 {
+		t.Errorf("expected email 7 to succeed on retry, got %+v", r)
+	}
+	if r := result.Results[42]; r.Err == nil || r.Attempts != 1 {
+		t.Errorf("expected email 42 to fail without retry, got %+v", r)
+	}
+	if r := result.Results[100]; !errors.Is(r.Err, ErrValidation) || r.Attempts != 0 {
+		t.Errorf("expected email 100 to fail validation, got %+v", r)
+	}
+	if attempts["100"] != 0 {
+		t.Error("expected invalid email not to be sent")
+	}
+}
+
+func TestBulkSenderMaxRetries(t *testing.T) {
+	client := New("sk_test_xxx", nil)
+	tests := []struct {
+		opts *BulkOptions
+		want int
+	}{
+		{nil, defaultBulkMaxRetries},
+		{&BulkOptions{Concurrency: 8}, defaultBulkMaxRetries},
+		{&BulkOptions{MaxRetries: 5}, 5},
+		{&BulkOptions{MaxRetries: -1}, 0},
+	}
+	for _, tt := range tests {
+		if got := NewBulkSender(client, tt.opts).maxRetries; got != tt.want {
+			t.Errorf("NewBulkSender(%+v).maxRetries = %d, want %d", tt.opts, got, tt.want)
+		}
+	}
+}
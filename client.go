@@ -4,6 +4,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 )
 
 // Client is the SendPigeon API client.
@@ -57,12 +58,17 @@ func New(apiKey string, opts *ClientOptions) *Client {
 //	    log.Fatal(err)
 //	}
 //	fmt.Println("Email ID:", resp.ID)
-func (c *Client) Send(ctx context.Context, req SendEmailRequest) (*SendEmailResponse, *Error) {
-	headers := make(map[string]string)
-	if req.IdempotencyKey != "" {
-		headers["Idempotency-Key"] = req.IdempotencyKey
+func (c *Client) Send(ctx context.Context, req SendEmailRequest) (*SendEmailResponse, error) {
+	req = c.http.withAutoText(req)
+	if err := c.http.validate(req.Validate); err != nil {
+		return nil, err
+	}
+	if err := c.http.checkAttachmentLimits("", req.Attachments); err != nil {
+		return nil, err
 	}
 
+	headers := c.http.idempotencyHeaders(req.IdempotencyKey)
+
 	body, err := c.http.Post(ctx, "/v1/emails", req, headers)
 	if err != nil {
 		return nil, err
@@ -70,13 +76,14 @@ func (c *Client) Send(ctx context.Context, req SendEmailRequest) (*SendEmailResp
 
 	var resp SendEmailResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
+	resp.IdempotencyKey = headers[idempotencyHeader]
 
 	return &resp, nil
 }
 
-// SendBatch sends multiple emails in a single request (max 100).
+// SendBatch sends multiple emails in a single request (max MaxBatchSize).
 //
 // Example:
 //
@@ -84,16 +91,37 @@ func (c *Client) Send(ctx context.Context, req SendEmailRequest) (*SendEmailResp
 //	    {To: []string{"user1@example.com"}, Subject: "Hello", HTML: "<p>Hi User 1!</p>"},
 //	    {To: []string{"user2@example.com"}, Subject: "Hello", HTML: "<p>Hi User 2!</p>"},
 //	})
-func (c *Client) SendBatch(ctx context.Context, emails []SendEmailRequest) (*SendBatchResponse, *Error) {
-	body, err := c.http.Post(ctx, "/v1/emails/batch", map[string]interface{}{"emails": emails}, nil)
+func (c *Client) SendBatch(ctx context.Context, emails []SendEmailRequest) (*SendBatchResponse, error) {
+	if err := c.http.validate(func() error { return validateBatch(emails) }); err != nil {
+		return nil, err
+	}
+	for i, email := range emails {
+		if err := c.http.checkAttachmentLimits(fmt.Sprintf("emails[%d].", i), email.Attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.http.autoText {
+		// Copy so the caller's slice is left untouched
+		filled := make([]SendEmailRequest, len(emails))
+		for i, email := range emails {
+			filled[i] = c.http.withAutoText(email)
+		}
+		emails = filled
+	}
+
+	headers := c.http.idempotencyHeaders("")
+
+	body, err := c.http.Post(ctx, "/v1/emails/batch", map[string]interface{}{"emails": emails}, headers)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp SendBatchResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
+	resp.IdempotencyKey = headers[idempotencyHeader]
 
 	return &resp, nil
 }
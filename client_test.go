@@ -3,9 +3,12 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -92,6 +95,7 @@ func TestSendWithIdempotencyKey(t *testing.T) {
 	_, err := client.Send(context.Background(), SendEmailRequest{
 		To:             []string{"user@example.com"},
 		Subject:        "Hello",
+		HTML:           "<p>Hi</p>",
 		IdempotencyKey: "unique-key-123",
 	})
 
@@ -103,6 +107,49 @@ func TestSendWithIdempotencyKey(t *testing.T) {
 	}
 }
 
+func TestSendAutoIdempotency(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(502)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "email_123", "status": "pending"})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:         server.URL,
+		AutoIdempotency: true,
+		RetryPolicy:     &DefaultRetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond},
+	})
+	resp, err := client.Send(context.Background(), SendEmailRequest{
+		To:      []string{"user@example.com"},
+		Subject: "Hello",
+		HTML:    "<p>Hi</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected the same generated key on every attempt, got %q and %q", keys[0], keys[1])
+	}
+	if resp.IdempotencyKey != keys[0] {
+		t.Errorf("expected response key %q, got %q", keys[0], resp.IdempotencyKey)
+	}
+
+	// A fresh key is generated per logical call.
+	client.Send(context.Background(), SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hi</p>"})
+	if keys[2] == keys[0] {
+		t.Error("expected a new key for a new call")
+	}
+}
+
 func TestSendBatch(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/v1/emails/batch" {
@@ -122,8 +169,8 @@ func TestSendBatch(t *testing.T) {
 
 	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
 	resp, err := client.SendBatch(context.Background(), []SendEmailRequest{
-		{To: []string{"user1@example.com"}, Subject: "Hello 1"},
-		{To: []string{"user2@example.com"}, Subject: "Hello 2"},
+		{To: []string{"user1@example.com"}, Subject: "Hello 1", HTML: "<p>Hi 1</p>"},
+		{To: []string{"user2@example.com"}, Subject: "Hello 2", HTML: "<p>Hi 2</p>"},
 	})
 
 	if err != nil {
@@ -134,6 +181,47 @@ func TestSendBatch(t *testing.T) {
 	}
 }
 
+func TestSendBatchPartialFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"data": [
+				{"index": 0, "status": "sent", "id": "email_1"},
+				{"index": 1, "status": "failed", "error": {"code": "invalid_recipient", "message": "mailbox does not exist"}},
+				{"index": 2, "status": "suppressed", "id": "email_3", "suppressed": ["user3@example.com"]}
+			],
+			"summary": {"total": 3, "sent": 1, "failed": 1, "suppressed": 1}
+		}`))
+	}))
+	defer server.Close()
+
+	emails := []SendEmailRequest{
+		{To: []string{"user1@example.com"}, Subject: "Hello 1", HTML: "<p>Hi 1</p>"},
+		{To: []string{"user2@example.com"}, Subject: "Hello 2", HTML: "<p>Hi 2</p>"},
+		{To: []string{"user3@example.com"}, Subject: "Hello 3", HTML: "<p>Hi 3</p>"},
+	}
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	resp, err := client.SendBatch(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Summary != (BatchSummary{Total: 3, Sent: 1, Failed: 1, Suppressed: 1}) {
+		t.Errorf("unexpected summary: %+v", resp.Summary)
+	}
+	failed := resp.Failed()
+	if len(failed) != 1 || failed[0].Status != BatchEmailStatusFailed || failed[0].Error.Code != "invalid_recipient" {
+		t.Errorf("unexpected failed results: %+v", failed)
+	}
+	if succeeded := resp.Succeeded(); len(succeeded) != 2 {
+		t.Errorf("expected 2 succeeded results, got %+v", succeeded)
+	}
+	retry := resp.ToRequests(emails)
+	if len(retry) != 1 || retry[0].Subject != "Hello 2" {
+		t.Errorf("unexpected requests to resubmit: %+v", retry)
+	}
+}
+
 func TestAPIError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -147,7 +235,7 @@ func TestAPIError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL, DisableValidation: true})
 	_, err := client.Send(context.Background(), SendEmailRequest{
 		To: []string{"user@example.com"},
 	})
@@ -155,13 +243,347 @@ func TestAPIError(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error")
 	}
-	if err.Code != ErrorCodeAPI {
-		t.Errorf("expected api_error, got %s", err.Code)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if apiErr.Code != ErrorCodeAPI {
+		t.Errorf("expected api_error, got %s", apiErr.Code)
+	}
+	if apiErr.APICode != "validation_error" {
+		t.Errorf("expected validation_error, got %s", apiErr.APICode)
+	}
+	if apiErr.Status != 400 {
+		t.Errorf("expected status 400, got %d", apiErr.Status)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Error("expected errors.Is(err, ErrValidation)")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("did not expect errors.Is(err, ErrNotFound)")
+	}
+	if IsRetryable(err) {
+		t.Error("did not expect validation error to be retryable")
 	}
-	if err.APICode != "validation_error" {
-		t.Errorf("expected validation_error, got %s", err.APICode)
+}
+
+func TestAPIValidationDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req_123")
+		w.WriteHeader(422)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    "validation_error",
+				"message": "Invalid request",
+				"details": []map[string]interface{}{
+					{"field": "to[1]", "rule": "email", "message": "Invalid email address"},
+					{"path": "variables.name", "code": "required", "message": "Missing variable"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL, DisableValidation: true})
+	_, err := client.Templates.Create(context.Background(), CreateTemplateRequest{Subject: "Hi"})
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(validationErr.Fields) != 2 {
+		t.Fatalf("expected 2 field errors, got %d", len(validationErr.Fields))
+	}
+	if f := validationErr.Fields[0]; f.Field != "to[1]" || f.Rule != "email" || f.Message != "Invalid email address" {
+		t.Errorf("unexpected field error: %+v", f)
+	}
+	if f := validationErr.Fields[1]; f.Field != "variables.name" || f.Rule != "required" {
+		t.Errorf("unexpected field error: %+v", f)
+	}
+
+	var apiErr *Error
+	errors.As(err, &apiErr)
+	if apiErr.RequestID != "req_123" {
+		t.Errorf("expected request ID req_123, got %q", apiErr.RequestID)
+	}
+	if apiErr.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected response headers, got %v", apiErr.Header)
+	}
+	if len(apiErr.Body) == 0 {
+		t.Error("expected raw body")
+	}
+}
+
+func TestErrorSentinels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/templates/missing":
+			w.WriteHeader(404)
+		case "/v1/templates/broken":
+			w.Write([]byte("not json"))
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+
+	_, err := client.Templates.Get(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	_, err = client.Templates.Get(context.Background(), "broken")
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("expected error to unwrap to *json.SyntaxError, got %v", err)
+	}
+
+	// A successful call must return an untyped nil error.
+	var deleteErr error = client.Templates.Delete(context.Background(), "tmpl_123")
+	if deleteErr != nil {
+		t.Errorf("expected nil error, got %#v", deleteErr)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(NewAPIError(503, "", "unavailable")) {
+		t.Error("expected 503 to be retryable")
+	}
+	if !IsRetryable(fmt.Errorf("wrapped: %w", NewAPIError(429, "", "slow down"))) {
+		t.Error("expected wrapped 429 to be retryable")
+	}
+	if IsRetryable(NewAPIError(404, "", "not found")) {
+		t.Error("did not expect 404 to be retryable")
+	}
+	if IsRetryable(errors.New("other")) {
+		t.Error("did not expect foreign error to be retryable")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("expected X-Tenant acme, got %q", r.Header.Get("X-Tenant"))
+		}
+		if attempts == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "email_123", "status": "pending"})
+	}))
+	defer server.Close()
+
+	var calls []Call
+	var errs []*Error
+	tenant := func(next CallHandler) CallHandler {
+		return func(call *Call) *CallResult {
+			call.Request.Header.Set("X-Tenant", "acme")
+			return next(call)
+		}
+	}
+	audit := func(next CallHandler) CallHandler {
+		return func(call *Call) *CallResult {
+			result := next(call)
+			calls = append(calls, *call)
+			errs = append(errs, result.Err)
+			return result
+		}
+	}
+
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:    server.URL,
+		MaxRetries: 1,
+		Middleware: []Middleware{audit, tenant},
+	})
+	_, err := client.Send(context.Background(), SendEmailRequest{
+		To:      []string{"user@example.com"},
+		Subject: "Hello",
+		HTML:    "<p>Hi</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].Service != "emails" || calls[0].Method != "POST" || calls[0].Path != "/v1/emails" {
+		t.Errorf("unexpected call: %+v", calls[0])
+	}
+	if calls[0].Attempt != 0 || calls[1].Attempt != 1 {
+		t.Errorf("unexpected attempts: %d, %d", calls[0].Attempt, calls[1].Attempt)
+	}
+	if errs[0] == nil || errs[0].Status != 500 {
+		t.Errorf("expected 500 error on first attempt, got %v", errs[0])
+	}
+	if errs[1] != nil {
+		t.Errorf("expected no error on second attempt, got %v", errs[1])
+	}
+}
+
+func TestEmailsList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/emails" {
+			t.Errorf("expected /v1/emails, got %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		expected := map[string]string{
+			"status":         "bounced",
+			"to":             "@customer.com",
+			"tag":            "receipt",
+			"metadata[plan]": "pro",
+			"created_after":  "2024-01-15T00:00:00Z",
+			"limit":          "50",
+		}
+		for k, v := range expected {
+			if q.Get(k) != v {
+				t.Errorf("expected %s=%s, got %q", k, v, q.Get(k))
+			}
+		}
+		if q.Has("created_before") {
+			t.Error("did not expect zero time to be sent")
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":   []map[string]interface{}{{"id": "email_1", "status": "bounced"}},
+			"cursor": map[string]string{"next": "abc"},
+		})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	resp, err := client.Emails.List(context.Background(), &ListEmailsOptions{
+		Limit:        50,
+		Status:       EmailStatusBounced,
+		To:           "@customer.com",
+		Tag:          "receipt",
+		Metadata:     map[string]string{"plan": "pro"},
+		CreatedAfter: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Status != EmailStatusBounced {
+		t.Errorf("unexpected data: %+v", resp.Data)
+	}
+	if resp.Cursor.Next != "abc" {
+		t.Errorf("expected next cursor abc, got %q", resp.Cursor.Next)
+	}
+}
+
+func TestEmailsAllCursorOnly(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Has("offset") {
+			t.Errorf("unexpected offset in %q", r.URL.RawQuery)
+		}
+		var body map[string]interface{}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			body = map[string]interface{}{
+				"data":   []map[string]interface{}{{"id": "email_1"}, {"id": "email_2"}},
+				"cursor": map[string]string{"next": "abc"},
+			}
+		case "abc":
+			body = map[string]interface{}{"data": []map[string]interface{}{{"id": "email_3"}}}
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	var ids []string
+	for email, err := range client.Emails.All(context.Background(), nil, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, email.ID)
+	}
+	if len(ids) != 3 || ids[2] != "email_3" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestEmailsEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/emails/email_123/events" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"type": "clicked", "timestamp": "2024-01-15T10:05:00Z", "link_url": "https://example.com", "link_index": 0},
+				{"type": "queued", "timestamp": "2024-01-15T10:00:00Z"},
+				{"type": "delivered", "timestamp": "2024-01-15T10:00:02Z"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	events, err := client.Emails.Events(context.Background(), "email_123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Type != EmailEventQueued || events[1].Type != EmailEventDelivered || events[2].Type != EmailEventClicked {
+		t.Errorf("expected events in chronological order, got %v, %v, %v", events[0].Type, events[1].Type, events[2].Type)
+	}
+	if events[2].LinkURL != "https://example.com" || events[2].LinkIndex == nil || *events[2].LinkIndex != 0 {
+		t.Errorf("unexpected click event: %+v", events[2])
+	}
+}
+
+func TestEmailsWaitForStatus(t *testing.T) {
+	statuses := []string{"pending", "pending", "sent", "delivered"}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[polls]
+		polls++
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "email_123", "status": status})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	result, err := client.Emails.WaitForStatus(context.Background(), "email_123", &WaitOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Email.Status != EmailStatusDelivered {
+		t.Errorf("expected delivered, got %s", result.Email.Status)
+	}
+	if polls != 4 {
+		t.Errorf("expected 4 polls, got %d", polls)
+	}
+	var got []string
+	for _, tr := range result.Transitions {
+		got = append(got, string(tr.From)+"->"+string(tr.To))
+	}
+	if fmt.Sprint(got) != "[->pending pending->sent sent->delivered]" {
+		t.Errorf("unexpected transitions: %v", got)
+	}
+
+	// Waiting for delivery of a bounced email fails fast.
+	statuses, polls = []string{"bounced"}, 0
+	result, err = client.Emails.WaitForStatus(context.Background(), "email_123", &WaitOptions{
+		Statuses: []EmailStatus{EmailStatusDelivered},
+	})
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Errorf("expected ErrUnexpectedStatus, got %v", err)
 	}
-	if err.Status != 400 {
-		t.Errorf("expected status 400, got %d", err.Status)
+	if result.Email == nil || result.Email.Status != EmailStatusBounced {
+		t.Errorf("expected bounced email in result, got %+v", result.Email)
 	}
 }
@@ -3,9 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
-	"net/url"
-	"strconv"
-	"strings"
+	"iter"
 )
 
 // ContactsService handles contact operations.
@@ -14,48 +12,26 @@ type ContactsService struct {
 }
 
 // List lists contacts with optional filtering.
-func (s *ContactsService) List(ctx context.Context, opts *ListContactsOptions) (*ListResponse[Contact], *Error) {
-	path := "/v1/contacts"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if len(opts.Tags) > 0 {
-			params.Set("tags", strings.Join(opts.Tags, ","))
-		}
-		if opts.Status != "" {
-			params.Set("status", opts.Status)
-		}
-		if opts.Search != "" {
-			params.Set("search", opts.Search)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[Contact]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *ContactsService) List(ctx context.Context, opts *ListContactsOptions) (*ListResponse[Contact], error) {
+	return getList[Contact](ctx, s.http, withQuery("/v1/contacts", opts.values()))
+}
 
-	return &resp, nil
+// All iterates over all contacts matching opts, fetching pages as needed.
+//
+// Example:
+//
+//	for contact, err := range client.Contacts.All(ctx, &sendpigeon.ListContactsOptions{Limit: 100}, nil) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(contact.Email)
+//	}
+func (s *ContactsService) All(ctx context.Context, opts *ListContactsOptions, iterOpts *IterOptions) iter.Seq2[Contact, error] {
+	return paginate(ctx, opts.listOptions(), listPages[Contact](s.http, "/v1/contacts", opts.values()), iterOpts)
 }
 
 // Stats returns audience statistics.
-func (s *ContactsService) Stats(ctx context.Context) (*AudienceStats, *Error) {
+func (s *ContactsService) Stats(ctx context.Context) (*AudienceStats, error) {
 	body, err := s.http.Get(ctx, "/v1/contacts/stats", nil)
 	if err != nil {
 		return nil, err
@@ -63,14 +39,14 @@ func (s *ContactsService) Stats(ctx context.Context) (*AudienceStats, *Error) {
 
 	var resp AudienceStats
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Tags returns all unique tags.
-func (s *ContactsService) Tags(ctx context.Context) ([]string, *Error) {
+func (s *ContactsService) Tags(ctx context.Context) ([]string, error) {
 	body, err := s.http.Get(ctx, "/v1/contacts/tags", nil)
 	if err != nil {
 		return nil, err
@@ -80,14 +56,18 @@ func (s *ContactsService) Tags(ctx context.Context) ([]string, *Error) {
 		Data []string `json:"data"`
 	}
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return resp.Data, nil
 }
 
 // Create creates a new contact.
-func (s *ContactsService) Create(ctx context.Context, req CreateContactRequest) (*Contact, *Error) {
+func (s *ContactsService) Create(ctx context.Context, req CreateContactRequest) (*Contact, error) {
+	if err := s.http.validate(req.Validate); err != nil {
+		return nil, err
+	}
+
 	body, err := s.http.Post(ctx, "/v1/contacts", req, nil)
 	if err != nil {
 		return nil, err
@@ -95,29 +75,36 @@ func (s *ContactsService) Create(ctx context.Context, req CreateContactRequest)
 
 	var resp Contact
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Batch creates or updates multiple contacts.
-func (s *ContactsService) Batch(ctx context.Context, contacts []BatchContactInput) (*BatchContactResponse, *Error) {
-	body, err := s.http.Post(ctx, "/v1/contacts/batch", map[string]interface{}{"contacts": contacts}, nil)
+func (s *ContactsService) Batch(ctx context.Context, contacts []BatchContactInput) (*BatchContactResponse, error) {
+	if err := s.http.validate(func() error { return validateContacts(contacts) }); err != nil {
+		return nil, err
+	}
+
+	headers := s.http.idempotencyHeaders("")
+
+	body, err := s.http.Post(ctx, "/v1/contacts/batch", map[string]interface{}{"contacts": contacts}, headers)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp BatchContactResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
+	resp.IdempotencyKey = headers[idempotencyHeader]
 
 	return &resp, nil
 }
 
 // Get retrieves a contact by ID.
-func (s *ContactsService) Get(ctx context.Context, id string) (*Contact, *Error) {
+func (s *ContactsService) Get(ctx context.Context, id string) (*Contact, error) {
 	body, err := s.http.Get(ctx, "/v1/contacts/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -125,14 +112,14 @@ func (s *ContactsService) Get(ctx context.Context, id string) (*Contact, *Error)
 
 	var resp Contact
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Update updates a contact.
-func (s *ContactsService) Update(ctx context.Context, id string, req UpdateContactRequest) (*Contact, *Error) {
+func (s *ContactsService) Update(ctx context.Context, id string, req UpdateContactRequest) (*Contact, error) {
 	body, err := s.http.Patch(ctx, "/v1/contacts/"+id, req, nil)
 	if err != nil {
 		return nil, err
@@ -140,20 +127,20 @@ func (s *ContactsService) Update(ctx context.Context, id string, req UpdateConta
 
 	var resp Contact
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Delete removes a contact.
-func (s *ContactsService) Delete(ctx context.Context, id string) *Error {
+func (s *ContactsService) Delete(ctx context.Context, id string) error {
 	_, err := s.http.Delete(ctx, "/v1/contacts/"+id, nil)
 	return err
 }
 
 // Unsubscribe unsubscribes a contact.
-func (s *ContactsService) Unsubscribe(ctx context.Context, id string) (*Contact, *Error) {
+func (s *ContactsService) Unsubscribe(ctx context.Context, id string) (*Contact, error) {
 	body, err := s.http.Post(ctx, "/v1/contacts/"+id+"/unsubscribe", nil, nil)
 	if err != nil {
 		return nil, err
@@ -161,14 +148,14 @@ func (s *ContactsService) Unsubscribe(ctx context.Context, id string) (*Contact,
 
 	var resp Contact
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Resubscribe resubscribes a contact.
-func (s *ContactsService) Resubscribe(ctx context.Context, id string) (*Contact, *Error) {
+func (s *ContactsService) Resubscribe(ctx context.Context, id string) (*Contact, error) {
 	body, err := s.http.Post(ctx, "/v1/contacts/"+id+"/resubscribe", nil, nil)
 	if err != nil {
 		return nil, err
@@ -176,7 +163,7 @@ func (s *ContactsService) Resubscribe(ctx context.Context, id string) (*Contact,
 
 	var resp Contact
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
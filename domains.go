@@ -3,8 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
-	"net/url"
-	"strconv"
+	"iter"
 )
 
 // DomainsService handles domain operations.
@@ -13,7 +12,7 @@ type DomainsService struct {
 }
 
 // Create adds a new domain.
-func (s *DomainsService) Create(ctx context.Context, name string) (*DomainWithDNSRecords, *Error) {
+func (s *DomainsService) Create(ctx context.Context, name string) (*DomainWithDNSRecords, error) {
 	body, err := s.http.Post(ctx, "/v1/domains", map[string]string{"name": name}, nil)
 	if err != nil {
 		return nil, err
@@ -21,14 +20,14 @@ func (s *DomainsService) Create(ctx context.Context, name string) (*DomainWithDN
 
 	var resp DomainWithDNSRecords
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Get retrieves a domain by ID.
-func (s *DomainsService) Get(ctx context.Context, id string) (*DomainWithDNSRecords, *Error) {
+func (s *DomainsService) Get(ctx context.Context, id string) (*DomainWithDNSRecords, error) {
 	body, err := s.http.Get(ctx, "/v1/domains/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -36,46 +35,24 @@ func (s *DomainsService) Get(ctx context.Context, id string) (*DomainWithDNSReco
 
 	var resp DomainWithDNSRecords
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // List lists all domains.
-func (s *DomainsService) List(ctx context.Context, opts *ListOptions) (*ListResponse[Domain], *Error) {
-	path := "/v1/domains"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[Domain]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *DomainsService) List(ctx context.Context, opts *ListOptions) (*ListResponse[Domain], error) {
+	return getList[Domain](ctx, s.http, withQuery("/v1/domains", opts.values()))
+}
 
-	return &resp, nil
+// All iterates over all domains, fetching pages as needed.
+func (s *DomainsService) All(ctx context.Context, opts *ListOptions, iterOpts *IterOptions) iter.Seq2[Domain, error] {
+	return paginate(ctx, opts, listPages[Domain](s.http, "/v1/domains", opts.values()), iterOpts)
 }
 
 // Verify triggers domain verification.
-func (s *DomainsService) Verify(ctx context.Context, id string) (*DomainVerificationResult, *Error) {
+func (s *DomainsService) Verify(ctx context.Context, id string) (*DomainVerificationResult, error) {
 	body, err := s.http.Post(ctx, "/v1/domains/"+id+"/verify", nil, nil)
 	if err != nil {
 		return nil, err
@@ -83,14 +60,14 @@ func (s *DomainsService) Verify(ctx context.Context, id string) (*DomainVerifica
 
 	var resp DomainVerificationResult
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Delete removes a domain.
-func (s *DomainsService) Delete(ctx context.Context, id string) *Error {
+func (s *DomainsService) Delete(ctx context.Context, id string) error {
 	_, err := s.http.Delete(ctx, "/v1/domains/"+id, nil)
 	return err
 }
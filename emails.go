@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
+	"sort"
+	"time"
 )
 
 // EmailsService handles email operations.
@@ -12,7 +15,7 @@ type EmailsService struct {
 }
 
 // Get retrieves an email by ID.
-func (s *EmailsService) Get(ctx context.Context, id string) (*EmailDetail, *Error) {
+func (s *EmailsService) Get(ctx context.Context, id string) (*EmailDetail, error) {
 	body, err := s.http.Get(ctx, "/v1/emails/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -20,12 +23,136 @@ func (s *EmailsService) Get(ctx context.Context, id string) (*EmailDetail, *Erro
 
 	var resp EmailDetail
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
+// Events returns the delivery timeline of an email, oldest event first.
+//
+// Example:
+//
+//	events, err := client.Emails.Events(ctx, "email_xxx")
+//	for _, e := range events {
+//	    fmt.Println(e.Timestamp, e.Type, e.DiagnosticCode)
+//	}
+func (s *EmailsService) Events(ctx context.Context, id string) ([]EmailEvent, error) {
+	body, err := s.http.Get(ctx, "/v1/emails/"+id+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []EmailEvent `json:"data"`
+	}
+	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
+	}
+
+	sort.SliceStable(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Timestamp.Before(resp.Data[j].Timestamp.Time)
+	})
+
+	return resp.Data, nil
+}
+
+// WaitForStatus polls an email until it reaches one of the statuses in opts
+// (by default any terminal status), backing off between polls. It returns the
+// final email and every status transition observed. If the email reaches a
+// terminal status that was not asked for, the error is ErrUnexpectedStatus.
+// On error the result holds whatever was observed so far.
+//
+// Example:
+//
+//	resp, _ := client.Send(ctx, req)
+//	result, err := client.Emails.WaitForStatus(ctx, resp.ID, nil)
+//	if err == nil && result.Email.Status == sendpigeon.EmailStatusDelivered {
+//	    fmt.Println("delivered")
+//	}
+func (s *EmailsService) WaitForStatus(ctx context.Context, id string, opts *WaitOptions) (*WaitResult, error) {
+	var statuses []EmailStatus
+	interval := time.Second
+	maxInterval := 30 * time.Second
+	if opts != nil {
+		statuses = opts.Statuses
+		if opts.Interval > 0 {
+			interval = opts.Interval
+		}
+		if opts.MaxInterval > 0 {
+			maxInterval = opts.MaxInterval
+		}
+	}
+
+	wanted := func(status EmailStatus) bool {
+		if len(statuses) == 0 {
+			return status.Terminal()
+		}
+		for _, s := range statuses {
+			if status == s {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := &WaitResult{}
+	for {
+		email, err := s.Get(ctx, id)
+		if err != nil {
+			return result, err
+		}
+
+		var prev EmailStatus
+		if result.Email != nil {
+			prev = result.Email.Status
+		}
+		if result.Email == nil || email.Status != prev {
+			result.Transitions = append(result.Transitions, StatusTransition{
+				From:       prev,
+				To:         email.Status,
+				ObservedAt: time.Now(),
+			})
+		}
+		result.Email = email
+
+		if wanted(email.Status) {
+			return result, nil
+		}
+		if email.Status.Terminal() {
+			return result, fmt.Errorf("email %s ended in status %q: %w", id, email.Status, ErrUnexpectedStatus)
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return result, contextError(err)
+		}
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// List lists sent emails with optional filtering.
+//
+// Example:
+//
+//	// All bounced emails to customer.com in the last 24 hours
+//	emails, err := client.Emails.List(ctx, &sendpigeon.ListEmailsOptions{
+//	    Status:       sendpigeon.EmailStatusBounced,
+//	    To:           "@customer.com",
+//	    CreatedAfter: time.Now().Add(-24 * time.Hour),
+//	})
+func (s *EmailsService) List(ctx context.Context, opts *ListEmailsOptions) (*ListResponse[EmailDetail], error) {
+	return getList[EmailDetail](ctx, s.http, withQuery("/v1/emails", opts.values()))
+}
+
+// All iterates over all emails matching opts, following cursors until a
+// page comes back without one.
+func (s *EmailsService) All(ctx context.Context, opts *ListEmailsOptions, iterOpts *IterOptions) iter.Seq2[EmailDetail, error] {
+	return paginate(ctx, opts.listOptions(), cursorPages[EmailDetail](s.http, "/v1/emails", opts.values()), iterOpts)
+}
+
 // ListResponse represents a paginated list response.
 type ListResponse[T any] struct {
 	Data   []T `json:"data"`
@@ -35,7 +162,7 @@ type ListResponse[T any] struct {
 }
 
 // Cancel cancels a scheduled email.
-func (s *EmailsService) Cancel(ctx context.Context, id string) (*EmailDetail, *Error) {
+func (s *EmailsService) Cancel(ctx context.Context, id string) (*EmailDetail, error) {
 	body, err := s.http.Post(ctx, fmt.Sprintf("/v1/emails/%s/cancel", id), nil, nil)
 	if err != nil {
 		return nil, err
@@ -43,7 +170,7 @@ func (s *EmailsService) Cancel(ctx context.Context, id string) (*EmailDetail, *E
 
 	var resp EmailDetail
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
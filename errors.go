@@ -1,6 +1,11 @@
 package sendpigeon
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 // ErrorCode represents the type of error.
 type ErrorCode string
@@ -9,6 +14,25 @@ const (
 	ErrorCodeNetwork ErrorCode = "network_error"
 	ErrorCodeAPI     ErrorCode = "api_error"
 	ErrorCodeTimeout ErrorCode = "timeout_error"
+	// ErrorCodeValidation marks requests rejected by client-side validation
+	// before they were sent.
+	ErrorCodeValidation ErrorCode = "validation_error"
+)
+
+// Sentinel errors for use with errors.Is. They match an *Error by its HTTP
+// status or API code.
+var (
+	// ErrNotFound matches 404 responses.
+	ErrNotFound = errors.New("sendpigeon: not found")
+	// ErrUnauthorized matches 401 responses, e.g. a missing or revoked API key.
+	ErrUnauthorized = errors.New("sendpigeon: unauthorized")
+	// ErrRateLimited matches 429 responses.
+	ErrRateLimited = errors.New("sendpigeon: rate limited")
+	// ErrValidation matches 400 and 422 responses and validation_error API codes.
+	ErrValidation = errors.New("sendpigeon: validation failed")
+	// ErrUnexpectedStatus is returned by EmailsService.WaitForStatus when an
+	// email reaches a terminal status other than the ones waited for.
+	ErrUnexpectedStatus = errors.New("sendpigeon: unexpected terminal status")
 )
 
 // Error represents an error from the SendPigeon API or SDK.
@@ -17,6 +41,18 @@ type Error struct {
 	Code    ErrorCode `json:"code"`
 	APICode string    `json:"api_code,omitempty"`
 	Status  int       `json:"status,omitempty"`
+	// IdempotencyKey is the key sent with the failed request, if any.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// RequestID is the X-Request-Id response header, useful for support.
+	RequestID string `json:"request_id,omitempty"`
+	// Validation holds per-field details when the API rejected the request.
+	Validation *ValidationError `json:"validation,omitempty"`
+	// Body is the raw response body of a failed API call.
+	Body []byte `json:"-"`
+	// Header holds the response headers of a failed API call.
+	Header http.Header `json:"-"`
+
+	cause error
 }
 
 // Error implements the error interface.
@@ -27,6 +63,64 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the underlying cause, such as a network or JSON decoding
+// error, or a context error when the request was cancelled. For API
+// validation failures it returns the *ValidationError.
+func (e *Error) Unwrap() error {
+	if e.cause != nil {
+		return e.cause
+	}
+	if e.Validation != nil {
+		return e.Validation
+	}
+	return nil
+}
+
+// Is reports whether e matches one of the package's sentinel errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.Validation != nil ||
+			e.Status == http.StatusBadRequest ||
+			e.Status == http.StatusUnprocessableEntity ||
+			e.APICode == "validation_error"
+	}
+	return false
+}
+
+// FieldError describes a single invalid field.
+type FieldError struct {
+	// Field is the path of the field, e.g. "to[0]" or "variables.name".
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, e.g. "required" or "email".
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError holds per-field validation failures.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.Field == "" {
+			parts[i] = f.Message
+		} else {
+			parts[i] = f.Field + ": " + f.Message
+		}
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
 // NewError creates a new Error.
 func NewError(code ErrorCode, message string) *Error {
 	return &Error{
@@ -44,3 +138,22 @@ func NewAPIError(status int, apiCode, message string) *Error {
 		Message: message,
 	}
 }
+
+// wrapError creates a new Error that unwraps to cause.
+func wrapError(code ErrorCode, message string, cause error) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		cause:   cause,
+	}
+}
+
+// IsRetryable reports whether err is an *Error that the default retry policy
+// would retry: network errors, 429 and 5xx responses.
+func IsRetryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return (&DefaultRetryPolicy{}).Retryable(e)
+}
@@ -6,10 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"os"
-	"strconv"
 	"time"
 )
 
@@ -28,16 +26,49 @@ type ClientOptions struct {
 	MaxRetries int
 	Debug      bool
 	HTTPClient *http.Client
+	// RetryPolicy controls retries. Defaults to a DefaultRetryPolicy with
+	// MaxRetries retries.
+	RetryPolicy RetryPolicy
+	// AutoIdempotency generates an Idempotency-Key for mutating endpoints
+	// (sends, batch sends, broadcast send/schedule, contact batches) when the
+	// caller did not supply one, so retries cannot duplicate work.
+	AutoIdempotency bool
+	// DisableValidation skips client-side request validation and leaves all
+	// checks to the API.
+	DisableValidation bool
+	// MaxAttachmentSize rejects sends with an attachment larger than this
+	// many bytes before they reach the API. Zero means no limit.
+	MaxAttachmentSize int64
+	// MaxTotalAttachmentSize rejects sends whose attachments together exceed
+	// this many bytes. Zero means no limit.
+	MaxTotalAttachmentSize int64
+	// AutoText fills in SendEmailRequest.Text from the HTML body, using
+	// HTMLToText, when a send has HTML but no plain-text part.
+	AutoText bool
+	// Middleware wraps every request attempt. The first entry is outermost.
+	Middleware []Middleware
+	// RateLimits enables client-side rate limiting per endpoint group,
+	// shared by all services of the Client.
+	RateLimits *RateLimits
 }
 
 // httpClient handles HTTP requests with retry logic.
 type httpClient struct {
-	apiKey     string
-	baseURL    string
-	timeout    time.Duration
-	maxRetries int
-	debug      bool
-	client     *http.Client
+	apiKey  string
+	baseURL string
+	timeout time.Duration
+	retry   RetryPolicy
+	debug   bool
+	client  *http.Client
+	handler CallHandler
+	limiter *rateLimiter
+
+	autoIdempotency bool
+	skipValidation  bool
+	autoText        bool
+
+	maxAttachmentSize      int64
+	maxTotalAttachmentSize int64
 }
 
 func newHTTPClient(apiKey string, opts *ClientOptions) *httpClient {
@@ -46,6 +77,13 @@ func newHTTPClient(apiKey string, opts *ClientOptions) *httpClient {
 	maxRetries := defaultMaxRetries
 	debug := false
 	var client *http.Client
+	var retry RetryPolicy
+	var middleware []Middleware
+	var rateLimits *RateLimits
+	autoIdempotency := false
+	skipValidation := false
+	autoText := false
+	var maxAttachmentSize, maxTotalAttachmentSize int64
 
 	if opts != nil {
 		if opts.BaseURL != "" {
@@ -62,6 +100,14 @@ func newHTTPClient(apiKey string, opts *ClientOptions) *httpClient {
 		}
 		debug = opts.Debug
 		client = opts.HTTPClient
+		retry = opts.RetryPolicy
+		middleware = opts.Middleware
+		rateLimits = opts.RateLimits
+		autoIdempotency = opts.AutoIdempotency
+		skipValidation = opts.DisableValidation
+		autoText = opts.AutoText
+		maxAttachmentSize = opts.MaxAttachmentSize
+		maxTotalAttachmentSize = opts.MaxTotalAttachmentSize
 	}
 
 	// Check for dev mode if no explicit base URL was set
@@ -76,40 +122,71 @@ func newHTTPClient(apiKey string, opts *ClientOptions) *httpClient {
 		client = &http.Client{Timeout: timeout}
 	}
 
-	return &httpClient{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		timeout:    timeout,
-		maxRetries: maxRetries,
-		debug:      debug,
-		client:     client,
+	if retry == nil {
+		retry = &DefaultRetryPolicy{MaxRetries: maxRetries}
 	}
+
+	c := &httpClient{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		timeout: timeout,
+		retry:   retry,
+		debug:   debug,
+		client:  client,
+
+		autoIdempotency: autoIdempotency,
+		skipValidation:  skipValidation,
+		autoText:        autoText,
+
+		maxAttachmentSize:      maxAttachmentSize,
+		maxTotalAttachmentSize: maxTotalAttachmentSize,
+	}
+	c.limiter = newRateLimiter(rateLimits)
+	c.handler = chain(c.limiter.middleware(c.do), middleware)
+	return c
 }
 
 // request makes an HTTP request with retry logic.
-func (c *httpClient) request(ctx context.Context, method, path string, body interface{}, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) request(ctx context.Context, method, path string, body interface{}, headers map[string]string) ([]byte, error) {
+	respBody, err := c.requestWithRetry(ctx, method, path, body, headers)
+	if err != nil {
+		err.IdempotencyKey = headers[idempotencyHeader]
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// requestWithRetry runs the attempts of a single logical request.
+func (c *httpClient) requestWithRetry(ctx context.Context, method, path string, body interface{}, headers map[string]string) ([]byte, *Error) {
 	url := c.baseURL + path
 
-	var bodyReader io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
-			return nil, NewError(ErrorCodeNetwork, fmt.Sprintf("failed to marshal request body: %v", err))
+			return nil, wrapError(ErrorCodeNetwork, fmt.Sprintf("failed to marshal request body: %v", err), err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	service := serviceFromPath(path)
+	start := time.Now()
+	maxAttempts := c.retry.MaxAttempts()
+	if maxAttempts < 1 {
+		maxAttempts = 1
 	}
 
 	var lastErr *Error
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		// Reset body reader for retry
-		if body != nil {
-			jsonBody, _ := json.Marshal(body)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// Fresh body reader for every attempt
+		var bodyReader io.Reader
+		if jsonBody != nil {
 			bodyReader = bytes.NewReader(jsonBody)
 		}
 
 		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 		if err != nil {
-			return nil, NewError(ErrorCodeNetwork, fmt.Sprintf("failed to create request: %v", err))
+			return nil, wrapError(ErrorCodeNetwork, fmt.Sprintf("failed to create request: %v", err), err)
 		}
 
 		// Set headers
@@ -121,107 +198,156 @@ func (c *httpClient) request(ctx context.Context, method, path string, body inte
 			req.Header.Set(k, v)
 		}
 
-		resp, err := c.client.Do(req)
-		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				return nil, NewError(ErrorCodeTimeout, "request timed out")
-			}
-			lastErr = NewError(ErrorCodeNetwork, fmt.Sprintf("request failed: %v", err))
-			if attempt < c.maxRetries {
-				c.sleep(attempt, 0)
-				continue
-			}
-			return nil, lastErr
+		result := c.handler(&Call{
+			Service: service,
+			Method:  method,
+			Path:    path,
+			Attempt: attempt,
+			Request: req,
+		})
+		if result == nil {
+			result = &CallResult{Err: NewError(ErrorCodeNetwork, "middleware returned no result")}
 		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = NewError(ErrorCodeNetwork, fmt.Sprintf("failed to read response: %v", err))
-			continue
+		if result.Err == nil {
+			return result.Body, nil
 		}
+		lastErr = result.Err
 
-		// Success
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			return respBody, nil
+		// Should retry?
+		if ctx.Err() != nil {
+			return nil, contextError(ctx.Err())
+		}
+		if attempt+1 >= maxAttempts || !c.retry.Retryable(lastErr) {
+			return nil, lastErr
 		}
 
-		// Parse error response
-		var apiErr struct {
-			Error struct {
-				Code    string `json:"code"`
-				Message string `json:"message"`
-			} `json:"error"`
+		var retryAfter time.Duration
+		if result.Response != nil {
+			retryAfter = parseRetryAfter(result.Response.Header.Get("Retry-After"))
 		}
-		json.Unmarshal(respBody, &apiErr)
+		delay := c.retry.Backoff(attempt, retryAfter)
 
-		message := apiErr.Error.Message
-		if message == "" {
-			message = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		// Give up early rather than sleep past the caller's budget
+		if max := c.retry.MaxElapsedTime(); max > 0 && time.Since(start)+delay > max {
+			return nil, lastErr
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, lastErr
 		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, contextError(err)
+		}
+	}
 
-		lastErr = NewAPIError(resp.StatusCode, apiErr.Error.Code, message)
+	return nil, lastErr
+}
 
-		// Should retry?
-		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
-			if attempt < c.maxRetries {
-				retryAfter := c.parseRetryAfter(resp.Header.Get("Retry-After"))
-				c.sleep(attempt, retryAfter)
-				continue
-			}
+// do performs a single attempt. It is the innermost CallHandler.
+func (c *httpClient) do(call *Call) *CallResult {
+	ctx := call.Request.Context()
+
+	resp, err := c.client.Do(call.Request)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return &CallResult{Err: wrapError(ErrorCodeTimeout, "request timed out", err)}
+		}
+		return &CallResult{Err: wrapError(ErrorCodeNetwork, fmt.Sprintf("request failed: %v", err), err)}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return &CallResult{
+			Response: resp,
+			Err:      wrapError(ErrorCodeNetwork, fmt.Sprintf("failed to read response: %v", err), err),
 		}
+	}
 
-		return nil, lastErr
+	// Success
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return &CallResult{Response: resp, Body: respBody}
 	}
 
-	return nil, lastErr
+	return &CallResult{
+		Response: resp,
+		Body:     respBody,
+		Err:      parseAPIError(resp, respBody),
+	}
 }
 
-// sleep implements exponential backoff.
-func (c *httpClient) sleep(attempt int, retryAfter time.Duration) {
-	if retryAfter > 0 {
-		time.Sleep(retryAfter)
-		return
+// parseAPIError builds an *Error from a non-2xx response.
+func parseAPIError(resp *http.Response, body []byte) *Error {
+	var apiErr struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Details []struct {
+				Field   string `json:"field"`
+				Path    string `json:"path"`
+				Rule    string `json:"rule"`
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"details"`
+		} `json:"error"`
 	}
-	backoff := time.Duration(math.Pow(2, float64(attempt))) * 100 * time.Millisecond
-	if backoff > 10*time.Second {
-		backoff = 10 * time.Second
+	json.Unmarshal(body, &apiErr)
+
+	message := apiErr.Error.Message
+	if message == "" {
+		message = fmt.Sprintf("HTTP %d", resp.StatusCode)
 	}
-	time.Sleep(backoff)
-}
 
-// parseRetryAfter parses the Retry-After header.
-func (c *httpClient) parseRetryAfter(value string) time.Duration {
-	if value == "" {
-		return 0
+	err := NewAPIError(resp.StatusCode, apiErr.Error.Code, message)
+	err.RequestID = resp.Header.Get("X-Request-Id")
+	err.Body = body
+	err.Header = resp.Header
+
+	if len(apiErr.Error.Details) > 0 {
+		fields := make([]FieldError, len(apiErr.Error.Details))
+		for i, d := range apiErr.Error.Details {
+			fields[i] = FieldError{Field: d.Field, Rule: d.Rule, Message: d.Message}
+			if fields[i].Field == "" {
+				fields[i].Field = d.Path
+			}
+			if fields[i].Rule == "" {
+				fields[i].Rule = d.Code
+			}
+		}
+		err.Validation = &ValidationError{Fields: fields}
 	}
-	if seconds, err := strconv.Atoi(value); err == nil {
-		return time.Duration(seconds) * time.Second
+
+	return err
+}
+
+// contextError converts a context error into an *Error.
+func contextError(err error) *Error {
+	if err == context.DeadlineExceeded {
+		return wrapError(ErrorCodeTimeout, "request timed out", err)
 	}
-	return 0
+	return wrapError(ErrorCodeNetwork, fmt.Sprintf("request cancelled: %v", err), err)
 }
 
 // Get makes a GET request.
-func (c *httpClient) Get(ctx context.Context, path string, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) Get(ctx context.Context, path string, headers map[string]string) ([]byte, error) {
 	return c.request(ctx, http.MethodGet, path, nil, headers)
 }
 
 // Post makes a POST request.
-func (c *httpClient) Post(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) Post(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, error) {
 	return c.request(ctx, http.MethodPost, path, body, headers)
 }
 
 // Put makes a PUT request.
-func (c *httpClient) Put(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) Put(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, error) {
 	return c.request(ctx, http.MethodPut, path, body, headers)
 }
 
 // Patch makes a PATCH request.
-func (c *httpClient) Patch(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) Patch(ctx context.Context, path string, body interface{}, headers map[string]string) ([]byte, error) {
 	return c.request(ctx, http.MethodPatch, path, body, headers)
 }
 
 // Delete makes a DELETE request.
-func (c *httpClient) Delete(ctx context.Context, path string, headers map[string]string) ([]byte, *Error) {
+func (c *httpClient) Delete(ctx context.Context, path string, headers map[string]string) ([]byte, error) {
 	return c.request(ctx, http.MethodDelete, path, nil, headers)
 }
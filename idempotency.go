@@ -0,0 +1,32 @@
+package sendpigeon
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+// idempotencyHeaders returns request headers carrying key. When key is empty
+// and automatic idempotency is enabled, a new key is generated; it is reused
+// for every retry of the call.
+func (c *httpClient) idempotencyHeaders(key string) map[string]string {
+	if key == "" && c.autoIdempotency {
+		key = newIdempotencyKey()
+	}
+	if key == "" {
+		return nil
+	}
+	return map[string]string{idempotencyHeader: key}
+}
+
+// newIdempotencyKey returns a random UUIDv4 string.
+func newIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("sendpigeon: failed to generate idempotency key: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,521 @@
+package sendpigeon
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InboundEventReceived is the event type of inbound email webhooks.
+const InboundEventReceived = "email.received"
+
+// ReplyTokenMetadataKey is the Metadata key SetReplyToken stores the reply
+// token under, so the original email can be found with
+// ListEmailsOptions.Metadata.
+const ReplyTokenMetadataKey = "replyToken"
+
+// InboundEmail is an email received on an inbound address.
+type InboundEmail struct {
+	// ID identifies the webhook event.
+	ID         string
+	EmailID    string
+	ReceivedAt time.Time
+	From       mail.Address
+	To         []mail.Address
+	CC         []mail.Address
+	ReplyTo    []mail.Address
+	Subject    string
+	Text       string
+	HTML       string
+	Headers    mail.Header
+	// MessageID, InReplyTo and References identify the message and the
+	// thread it belongs to. They are given without angle brackets.
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	Attachments []InboundAttachment
+	Verdicts    InboundVerdicts
+}
+
+// InboundAttachment is an attachment of an InboundEmail.
+type InboundAttachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Size        int64
+	// Content is the decoded attachment content.
+	Content []byte
+}
+
+// InboundVerdict is the outcome of a check run on an inbound email.
+type InboundVerdict string
+
+const (
+	InboundVerdictPass InboundVerdict = "pass"
+	InboundVerdictFail InboundVerdict = "fail"
+	InboundVerdictGray InboundVerdict = "gray"
+	// InboundVerdictNone means the check was not run or did not complete.
+	InboundVerdictNone InboundVerdict = "none"
+)
+
+// InboundVerdicts are the spam, virus and authentication checks run on an
+// inbound email.
+type InboundVerdicts struct {
+	Spam  InboundVerdict
+	Virus InboundVerdict
+	SPF   InboundVerdict
+	DKIM  InboundVerdict
+	DMARC InboundVerdict
+	// SpamScore is the spam filter's score, higher meaning more likely
+	// spam. It is zero if not reported.
+	SpamScore float64
+}
+
+// IsSpam reports whether the email failed the spam or virus check.
+func (v InboundVerdicts) IsSpam() bool {
+	return v.Spam == InboundVerdictFail || v.Virus == InboundVerdictFail
+}
+
+// inboundPayload is the wire format of an inbound email webhook.
+type inboundPayload struct {
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	Type      string `json:"type"`
+	Timestamp Time   `json:"timestamp"`
+	Data      struct {
+		EmailID     string         `json:"emailId"`
+		ReceivedAt  Time           `json:"receivedAt"`
+		From        stringList     `json:"from"`
+		To          stringList     `json:"to"`
+		CC          stringList     `json:"cc"`
+		ReplyTo     stringList     `json:"replyTo"`
+		Subject     string         `json:"subject"`
+		Text        string         `json:"text"`
+		HTML        string         `json:"html"`
+		Headers     inboundHeaders `json:"headers"`
+		MessageID   string         `json:"messageId"`
+		InReplyTo   string         `json:"inReplyTo"`
+		References  stringList     `json:"references"`
+		Attachments []struct {
+			Filename    string `json:"filename"`
+			ContentType string `json:"contentType"`
+			ContentID   string `json:"contentId"`
+			Size        int64  `json:"size"`
+			Content     string `json:"content"`
+		} `json:"attachments"`
+		Verdicts struct {
+			Spam      string  `json:"spam"`
+			Virus     string  `json:"virus"`
+			SPF       string  `json:"spf"`
+			DKIM      string  `json:"dkim"`
+			DMARC     string  `json:"dmarc"`
+			SpamScore float64 `json:"spamScore"`
+		} `json:"verdicts"`
+	} `json:"data"`
+}
+
+// stringList decodes a JSON string or array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]string)(l))
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*l = nil
+	if s != "" {
+		*l = stringList{s}
+	}
+	return nil
+}
+
+// inboundHeaders decodes headers sent as an object of strings or string
+// arrays, or as an array of name/value pairs.
+type inboundHeaders mail.Header
+
+func (h *inboundHeaders) UnmarshalJSON(data []byte) error {
+	header := make(mail.Header)
+	add := func(name, value string) {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		header[name] = append(header[name], value)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var pairs []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(data, &pairs); err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			add(p.Name, p.Value)
+		}
+	} else {
+		var fields map[string]stringList
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		for name, values := range fields {
+			for _, v := range values {
+				add(name, v)
+			}
+		}
+	}
+	*h = inboundHeaders(header)
+	return nil
+}
+
+// ParseInboundEmail parses the payload of an inbound email webhook. Verify
+// the payload first with VerifyInboundWebhook.
+//
+// Example:
+//
+//	result := sendpigeon.VerifyInboundWebhook(body, signature, timestamp, "whsec_inbound_xxx", 300)
+//	if !result.Valid {
+//	    http.Error(w, result.Error, http.StatusUnauthorized)
+//	    return
+//	}
+//	email, err := sendpigeon.ParseInboundEmail(body)
+//	if err != nil {
+//	    http.Error(w, "invalid payload", http.StatusBadRequest)
+//	    return
+//	}
+//	if token, ok := email.ReplyToken("support@in.example.com"); ok {
+//	    addComment(token, email.From.Address, email.Reply())
+//	}
+func ParseInboundEmail(payload []byte) (*InboundEmail, error) {
+	var p inboundPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	event := p.Event
+	if event == "" {
+		event = p.Type
+	}
+	if event != "" && event != InboundEventReceived {
+		return nil, fmt.Errorf("sendpigeon: not an inbound email event: %q", event)
+	}
+
+	d := p.Data
+	e := &InboundEmail{
+		ID:         p.ID,
+		EmailID:    d.EmailID,
+		ReceivedAt: orTime(d.ReceivedAt, p.Timestamp).Time,
+		To:         parseAddresses(d.To),
+		CC:         parseAddresses(d.CC),
+		ReplyTo:    parseAddresses(d.ReplyTo),
+		Subject:    d.Subject,
+		Text:       d.Text,
+		HTML:       d.HTML,
+		Headers:    mail.Header(d.Headers),
+		Verdicts: InboundVerdicts{
+			Spam:      parseVerdict(d.Verdicts.Spam),
+			Virus:     parseVerdict(d.Verdicts.Virus),
+			SPF:       parseVerdict(d.Verdicts.SPF),
+			DKIM:      parseVerdict(d.Verdicts.DKIM),
+			DMARC:     parseVerdict(d.Verdicts.DMARC),
+			SpamScore: d.Verdicts.SpamScore,
+		},
+	}
+	if e.Headers == nil {
+		e.Headers = make(mail.Header)
+	}
+	if from := parseAddresses(d.From); len(from) > 0 {
+		e.From = from[0]
+	}
+
+	// Threading fields fall back to the raw headers
+	e.MessageID = trimMessageID(firstNonEmpty(d.MessageID, e.Headers.Get("Message-Id")))
+	e.InReplyTo = trimMessageID(firstNonEmpty(d.InReplyTo, e.Headers.Get("In-Reply-To")))
+	refs := []string(d.References)
+	if len(refs) == 0 {
+		refs = []string{e.Headers.Get("References")}
+	}
+	for _, r := range refs {
+		for _, id := range strings.Fields(r) {
+			e.References = append(e.References, trimMessageID(id))
+		}
+	}
+
+	for i, a := range d.Attachments {
+		content, err := decodeBase64(a.Content)
+		if err != nil {
+			return nil, fmt.Errorf("sendpigeon: attachment %d (%s): %w", i, a.Filename, err)
+		}
+		size := a.Size
+		if size == 0 {
+			size = int64(len(content))
+		}
+		e.Attachments = append(e.Attachments, InboundAttachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			ContentID:   trimMessageID(a.ContentID),
+			Size:        size,
+			Content:     content,
+		})
+	}
+	return e, nil
+}
+
+// parseAddresses parses address lists, keeping unparseable entries as bare
+// addresses rather than dropping them.
+func parseAddresses(values []string) []mail.Address {
+	var out []mail.Address
+	for _, v := range values {
+		list, err := mail.ParseAddressList(v)
+		if err != nil {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, mail.Address{Address: v})
+			}
+			continue
+		}
+		for _, a := range list {
+			out = append(out, *a)
+		}
+	}
+	return out
+}
+
+func parseVerdict(s string) InboundVerdict {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "pass":
+		return InboundVerdictPass
+	case "fail":
+		return InboundVerdictFail
+	case "gray", "grey":
+		return InboundVerdictGray
+	}
+	return InboundVerdictNone
+}
+
+func trimMessageID(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// decodeBase64 decodes padded or unpadded base64, ignoring line breaks.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.Join(strings.Fields(s), ""), "=")
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// Reply returns the new content of the email with quoted replies removed.
+// The HTML body is converted to text if the email has no text body.
+func (e *InboundEmail) Reply() string {
+	text := e.Text
+	if text == "" {
+		text = HTMLToText(e.HTML)
+	}
+	return StripQuotedReply(text)
+}
+
+// quoteHeaderPatterns match the first line of a quoted message.
+var quoteHeaderPatterns = []*regexp.Regexp{
+	// Gmail, Apple Mail: "On Mon, 1 Jan 2024, Jane <jane@example.com> wrote:",
+	// which clients may wrap onto a second line
+	regexp.MustCompile(`(?m)^[ \t]*On [^\n]*(?:\n[^\n]*)?wrote:[ \t]*$`),
+	// Outlook: "-----Original Message-----"
+	regexp.MustCompile(`(?mi)^[ \t]*-{2,}[ \t]*Original Message[ \t]*-{2,}`),
+	// Outlook: a separator line or a From:/Sent: header block
+	regexp.MustCompile(`(?m)^[ \t]*_{10,}[ \t]*$`),
+	regexp.MustCompile(`(?m)^[ \t]*\*?From:\*?[^\n]*\n[ \t]*\*?(?:Sent|Date):`),
+}
+
+// StripQuotedReply removes the quoted message from a plain-text reply: the
+// "On ... wrote:" block and everything after it, Outlook's original
+// message header, and any trailing lines quoted with ">".
+//
+// Example:
+//
+//	reply := sendpigeon.StripQuotedReply("Sounds good!\n\nOn Mon, Jan 1, 2024 at 9:00 AM Jane <jane@example.com> wrote:\n> Shall we meet?")
+//	// reply == "Sounds good!"
+func StripQuotedReply(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	cut := len(text)
+	for _, p := range quoteHeaderPatterns {
+		if loc := p.FindStringIndex(text); loc != nil && loc[0] < cut {
+			cut = loc[0]
+		}
+	}
+	lines := strings.Split(text[:cut], "\n")
+
+	// Drop the trailing quoted block
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line != "" && !strings.HasPrefix(line, ">") {
+			break
+		}
+		end--
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
+
+// NewReplyToken returns a random reply token. Anyone who knows a token can
+// post into its conversation by writing to the tagged address, so tokens
+// must be unguessable: use NewReplyToken rather than ticket numbers or
+// other IDs, and map the token to the conversation on your side.
+func NewReplyToken() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("sendpigeon: failed to generate reply token: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// ReplyAddress returns inboundAddress with token added as a plus tag, e.g.
+// "support+abc123@in.example.com" for "support@in.example.com". See
+// NewReplyToken for choosing the token.
+func ReplyAddress(inboundAddress, token string) (string, error) {
+	var errs fieldErrors
+	addr, err := mail.ParseAddress(inboundAddress)
+	if err != nil {
+		errs.add("replyTo", "email", fmt.Sprintf("invalid email address %q", inboundAddress))
+	}
+	if !validReplyToken(token) {
+		errs.add("replyToken", "format", "reply token must be non-empty and contain only letters, digits, '.', '_', '-' and '='")
+	}
+	if err := errs.err(); err != nil {
+		return "", err
+	}
+
+	local, domain, _ := strings.Cut(addr.Address, "@")
+	local, _, _ = strings.Cut(local, "+")
+	addr.Address = local + "+" + token + "@" + domain
+	return addr.String(), nil
+}
+
+// SetReplyToken routes replies to inboundAddress tagged with token (see
+// ReplyAddress) and records token in Metadata under
+// ReplyTokenMetadataKey. Read it back from the reply with
+// InboundEmail.ReplyToken. The token must be unguessable; see
+// NewReplyToken.
+//
+// Example:
+//
+//	token := sendpigeon.NewReplyToken()
+//	if err := saveReplyToken(ctx, token, ticket.ID); err != nil {
+//	    return err
+//	}
+//	req := sendpigeon.SendEmailRequest{
+//	    From:    "support@example.com",
+//	    To:      []string{"customer@example.com"},
+//	    Subject: "Re: Ticket #1234",
+//	    Text:    "We're looking into it.",
+//	}
+//	if err := req.SetReplyToken("support@in.example.com", token); err != nil {
+//	    return err
+//	}
+func (r *SendEmailRequest) SetReplyToken(inboundAddress, token string) error {
+	replyTo, err := ReplyAddress(inboundAddress, token)
+	if err != nil {
+		return err
+	}
+	r.ReplyTo = replyTo
+	if r.Metadata == nil {
+		r.Metadata = make(map[string]string)
+	}
+	r.Metadata[ReplyTokenMetadataKey] = token
+	return nil
+}
+
+// ParseReplyToken returns the plus tag of an address created by
+// ReplyAddress. It accepts any local part and domain; use
+// InboundEmail.ReplyToken to only accept your own inbound address.
+func ParseReplyToken(address string) (string, bool) {
+	if addr, err := mail.ParseAddress(address); err == nil {
+		address = addr.Address
+	}
+	local, _, ok := strings.Cut(address, "@")
+	if !ok {
+		return "", false
+	}
+	_, token, ok := strings.Cut(local, "+")
+	if !ok || !validReplyToken(token) {
+		return "", false
+	}
+	return token, true
+}
+
+// ReplyToken returns the reply token of the first recipient address that
+// ReplyAddress created from inboundAddress, looking at To, CC and then the
+// Delivered-To and X-Original-To headers. Tagged addresses with another
+// local part or domain are ignored, so a sender cannot pick the token by
+// also writing to an address of their own.
+func (e *InboundEmail) ReplyToken(inboundAddress string) (string, bool) {
+	inbound, err := mail.ParseAddress(inboundAddress)
+	if err != nil {
+		return "", false
+	}
+	local, domain, _ := strings.Cut(inbound.Address, "@")
+	local, _, _ = strings.Cut(local, "+")
+
+	match := func(address string) (string, bool) {
+		if addr, err := mail.ParseAddress(address); err == nil {
+			address = addr.Address
+		}
+		l, d, ok := strings.Cut(address, "@")
+		if !ok || !strings.EqualFold(d, domain) {
+			return "", false
+		}
+		l, token, ok := strings.Cut(l, "+")
+		if !ok || !strings.EqualFold(l, local) || !validReplyToken(token) {
+			return "", false
+		}
+		return token, true
+	}
+
+	for _, list := range [][]mail.Address{e.To, e.CC} {
+		for _, a := range list {
+			if token, ok := match(a.Address); ok {
+				return token, true
+			}
+		}
+	}
+	for _, h := range []string{"Delivered-To", "X-Original-To"} {
+		for _, v := range e.Headers[h] {
+			if token, ok := match(v); ok {
+				return token, true
+			}
+		}
+	}
+	return "", false
+}
+
+func validReplyToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, c := range token {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
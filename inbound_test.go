@@ -0,0 +1,128 @@
+package sendpigeon
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseInboundEmail(t *testing.T) {
+	payload := `{
+		"id": "evt_1",
+		"event": "email.received",
+		"timestamp": "2024-05-01T12:00:00Z",
+		"data": {
+			"emailId": "in_1",
+			"from": "Jane Doe <jane@example.com>",
+			"to": ["sales+forged@evil.example.com", "support+9c1f5e2a7b3d4c6e8f0a1b2c3d4e5f60@in.example.com"],
+			"cc": "Bob <bob@example.com>, carol@example.com",
+			"subject": "Re: Ticket #1234",
+			"text": "Thanks!\n\nOn Mon, Apr 29, 2024 at 9:00 AM Support <support@example.com> wrote:\n> We're on it.",
+			"headers": {"message-id": "<abc@mail.example.com>", "References": "<r1@example.com> <r2@example.com>"},
+			"inReplyTo": "<r2@example.com>",
+			"attachments": [{"filename": "log.txt", "contentType": "text/plain", "content": "aGVsbG8="}],
+			"verdicts": {"spam": "PASS", "virus": "PASS", "spf": "FAIL", "dkim": "gray", "spamScore": 1.5}
+		}
+	}`
+
+	e, err := ParseInboundEmail([]byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.ID != "evt_1" || e.EmailID != "in_1" || e.ReceivedAt.IsZero() {
+		t.Errorf("unexpected identity: %+v", e)
+	}
+	if e.From.Name != "Jane Doe" || e.From.Address != "jane@example.com" {
+		t.Errorf("From = %+v", e.From)
+	}
+	if len(e.CC) != 2 || e.CC[0].Name != "Bob" || e.CC[1].Address != "carol@example.com" {
+		t.Errorf("CC = %+v", e.CC)
+	}
+	if e.MessageID != "abc@mail.example.com" || e.InReplyTo != "r2@example.com" {
+		t.Errorf("MessageID = %q, InReplyTo = %q", e.MessageID, e.InReplyTo)
+	}
+	if strings.Join(e.References, " ") != "r1@example.com r2@example.com" {
+		t.Errorf("References = %v", e.References)
+	}
+	if len(e.Attachments) != 1 || string(e.Attachments[0].Content) != "hello" || e.Attachments[0].Size != 5 {
+		t.Errorf("Attachments = %+v", e.Attachments)
+	}
+	v := e.Verdicts
+	if v.Spam != InboundVerdictPass || v.SPF != InboundVerdictFail || v.DKIM != InboundVerdictGray || v.DMARC != InboundVerdictNone || v.SpamScore != 1.5 {
+		t.Errorf("Verdicts = %+v", v)
+	}
+	if v.IsSpam() {
+		t.Error("IsSpam = true")
+	}
+	if got := e.Reply(); got != "Thanks!" {
+		t.Errorf("Reply = %q", got)
+	}
+	if token, ok := e.ReplyToken("Support <support@in.example.com>"); !ok || token != "9c1f5e2a7b3d4c6e8f0a1b2c3d4e5f60" {
+		t.Errorf("ReplyToken = %q, %v", token, ok)
+	}
+	if token, ok := e.ReplyToken("help@in.example.com"); ok {
+		t.Errorf("ReplyToken for another inbound address = %q", token)
+	}
+}
+
+func TestParseInboundEmailErrors(t *testing.T) {
+	if _, err := ParseInboundEmail([]byte(`{"event":"email.delivered"}`)); err == nil {
+		t.Error("expected error for non-inbound event")
+	}
+	if _, err := ParseInboundEmail([]byte(`{"data":{"attachments":[{"content":"!!"}]}}`)); err == nil {
+		t.Error("expected error for invalid attachment content")
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"gmail", "Sounds good.\r\n\r\nOn Mon, Jan 1, 2024 at 9:00 AM Jane <jane@example.com> wrote:\r\n> Shall we meet?\r\n", "Sounds good."},
+		{"wrapped header", "Yes\n\nOn Mon, Jan 1, 2024 at 9:00 AM Jane Doe <jane@example.com>\nwrote:\n> Shall we meet?", "Yes"},
+		{"outlook", "Done.\n\n-----Original Message-----\nFrom: Jane\nSent: Monday\n\nPlease do it", "Done."},
+		{"outlook header block", "Done.\n\n________________________________\nFrom: Jane <jane@example.com>\nSent: Monday", "Done."},
+		{"trailing quote", "Agreed\n\n> earlier text\n> more", "Agreed"},
+		{"inline quotes kept", "> question one\nanswer one\n> question two\nanswer two", "> question one\nanswer one\n> question two\nanswer two"},
+		{"no quote", "Just a message.\nWith two lines.", "Just a message.\nWith two lines."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripQuotedReply(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetReplyToken(t *testing.T) {
+	token := NewReplyToken()
+	if len(token) != 32 || !validReplyToken(token) || token == NewReplyToken() {
+		t.Fatalf("NewReplyToken = %q", token)
+	}
+
+	req := SendEmailRequest{To: []string{"customer@example.com"}, Subject: "Hi", Text: "Hi"}
+	if err := req.SetReplyToken("Support <support+old@in.example.com>", token); err != nil {
+		t.Fatal(err)
+	}
+	if req.ReplyTo != `"Support" <support+`+token+`@in.example.com>` {
+		t.Errorf("ReplyTo = %q", req.ReplyTo)
+	}
+	if req.Metadata[ReplyTokenMetadataKey] != token {
+		t.Errorf("Metadata = %v", req.Metadata)
+	}
+	if got, ok := ParseReplyToken(req.ReplyTo); !ok || got != token {
+		t.Errorf("ParseReplyToken = %q, %v", got, ok)
+	}
+
+	var ve *ValidationError
+	if err := req.SetReplyToken("support@in.example.com", "bad token@"); !errors.As(err, &ve) {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+	if _, ok := ParseReplyToken("support@in.example.com"); ok {
+		t.Error("ParseReplyToken found a token in an untagged address")
+	}
+}
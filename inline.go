@@ -0,0 +1,191 @@
+package sendpigeon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// imgSrcPattern matches the src attribute of <img> tags. Group 1 is a
+// double-quoted value, group 2 single-quoted, group 3 unquoted.
+var imgSrcPattern = regexp.MustCompile(`(?is)<img\b[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// InlineAttachment marks a as inline and assigns it a Content-ID so it can
+// be referenced from HTML as "cid:<contentID>".
+func InlineAttachment(a Attachment, contentID string) Attachment {
+	a.ContentID = contentID
+	a.Disposition = AttachmentDispositionInline
+	return a
+}
+
+// EmbedImages finds <img> tags in htmlBody whose src is a local path or a
+// data: URI, converts each image into an inline attachment and rewrites the
+// src to a cid: reference. Local paths are resolved in fsys; when fsys is nil
+// only data: URIs are embedded. Remote (http, https) and cid: sources are
+// left untouched, and repeated sources share a single attachment.
+func EmbedImages(htmlBody string, fsys fs.FS) (string, []Attachment, error) {
+	matches := imgSrcPattern.FindAllStringSubmatchIndex(htmlBody, -1)
+	if len(matches) == 0 {
+		return htmlBody, nil, nil
+	}
+
+	var out strings.Builder
+	var attachments []Attachment
+	cids := make(map[string]string) // src -> content ID
+	embedded := make(map[string]bool)
+	last := 0
+
+	for _, m := range matches {
+		// Locate whichever value group matched
+		start, end := -1, -1
+		for g := 1; g <= 3; g++ {
+			if m[2*g] >= 0 {
+				start, end = m[2*g], m[2*g+1]
+				break
+			}
+		}
+		src := html.UnescapeString(htmlBody[start:end])
+
+		cid, ok := cids[src]
+		if !ok {
+			a, embed, err := inlineImage(src, fsys, len(attachments)+1)
+			if err != nil {
+				return "", nil, err
+			}
+			if !embed {
+				continue
+			}
+			cid = a.ContentID
+			cids[src] = cid
+			if !embedded[cid] {
+				embedded[cid] = true
+				attachments = append(attachments, a)
+			}
+		}
+
+		out.WriteString(htmlBody[last:start])
+		if m[6] >= 0 {
+			// Unquoted value; quoting keeps the result valid
+			out.WriteString(`"cid:` + cid + `"`)
+		} else {
+			out.WriteString("cid:" + cid)
+		}
+		last = end
+	}
+	out.WriteString(htmlBody[last:])
+
+	return out.String(), attachments, nil
+}
+
+// EmbedImages embeds local and data: URI images referenced by r.HTML as
+// inline attachments. See the package-level EmbedImages.
+//
+// Example:
+//
+//	req := sendpigeon.SendEmailRequest{
+//	    To:      []string{"user@example.com"},
+//	    Subject: "Your receipt",
+//	    HTML:    `<img src="images/logo.png"><p>Thanks!</p>`,
+//	}
+//	if err := req.EmbedImages(os.DirFS("templates")); err != nil {
+//	    log.Fatal(err)
+//	}
+func (r *SendEmailRequest) EmbedImages(fsys fs.FS) error {
+	body, attachments, err := EmbedImages(r.HTML, fsys)
+	if err != nil {
+		return err
+	}
+	r.HTML = body
+	r.Attachments = append(r.Attachments, attachments...)
+	return nil
+}
+
+// inlineImage loads the image at src. It reports false for sources that
+// should not be embedded.
+func inlineImage(src string, fsys fs.FS, n int) (Attachment, bool, error) {
+	src = strings.TrimSpace(src)
+	lower := strings.ToLower(src)
+
+	var a Attachment
+	var err error
+	switch {
+	case strings.HasPrefix(lower, "data:"):
+		a, err = dataURIAttachment(src, n)
+	case src == "", strings.HasPrefix(src, "//"), strings.Contains(lower, ":"):
+		// Remote URLs, cid: references and other schemes stay as they are
+		return Attachment{}, false, nil
+	case fsys == nil:
+		return Attachment{}, false, nil
+	default:
+		name := src
+		if i := strings.IndexAny(name, "?#"); i >= 0 {
+			name = name[:i]
+		}
+		if unescaped, uerr := url.PathUnescape(name); uerr == nil {
+			name = unescaped
+		}
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		a, err = AttachmentFromFS(fsys, name, nil)
+	}
+	if err != nil {
+		return Attachment{}, false, fmt.Errorf("embed image %q: %w", truncate(src, 64), err)
+	}
+
+	// Identical content gets the same Content-ID
+	return InlineAttachment(a, a.Checksum[:16]+"@sendpigeon"), true, nil
+}
+
+// dataURIAttachment decodes an RFC 2397 data: URI.
+func dataURIAttachment(src string, n int) (Attachment, error) {
+	comma := strings.IndexByte(src, ',')
+	if comma < 0 {
+		return Attachment{}, fmt.Errorf("malformed data URI")
+	}
+	meta, payload := src[len("data:"):comma], src[comma+1:]
+
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		isBase64 = true
+		meta = meta[:len(meta)-len(";base64")]
+	}
+	contentType := meta
+	if contentType == "" {
+		contentType = "text/plain;charset=US-ASCII"
+	}
+
+	var data []byte
+	var err error
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+	} else {
+		var s string
+		s, err = url.PathUnescape(payload)
+		data = []byte(s)
+	}
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	filename := fmt.Sprintf("image-%d", n)
+	if mediaType, _, perr := mime.ParseMediaType(contentType); perr == nil {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+
+	return AttachmentFromReader(filename, bytes.NewReader(data), &AttachmentOptions{ContentType: contentType})
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,54 @@
+package sendpigeon
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbedImages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/logo.png": {Data: []byte("\x89PNG\r\n\x1a\nlogo")},
+	}
+	req := SendEmailRequest{
+		HTML: `<img src="images/logo.png" alt="Logo">` +
+			`<img alt='again' src='./images/logo.png'>` +
+			`<img src=data:image/gif;base64,R0lGODlhAQABAAAAACw=>` +
+			`<img src="https://cdn.example.com/remote.png">` +
+			`<img src="cid:existing@example">`,
+	}
+
+	if err := req.EmbedImages(fsys); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(req.Attachments) != 2 {
+		t.Fatalf("expected 2 inline attachments, got %d", len(req.Attachments))
+	}
+	logo, gif := req.Attachments[0], req.Attachments[1]
+	if logo.Disposition != AttachmentDispositionInline || logo.ContentID == "" {
+		t.Errorf("expected inline attachment with content ID, got %+v", logo)
+	}
+	if logo.Filename != "logo.png" || logo.ContentType != "image/png" {
+		t.Errorf("unexpected logo attachment: %+v", logo)
+	}
+	if gif.ContentType != "image/gif" || !strings.HasPrefix(gif.Filename, "image-2") {
+		t.Errorf("unexpected data URI attachment: %+v", gif)
+	}
+
+	want := `<img src="cid:` + logo.ContentID + `" alt="Logo">` +
+		`<img alt='again' src='cid:` + logo.ContentID + `'>` +
+		`<img src="cid:` + gif.ContentID + `">` +
+		`<img src="https://cdn.example.com/remote.png">` +
+		`<img src="cid:existing@example">`
+	if req.HTML != want {
+		t.Errorf("unexpected HTML:\n got: %s\nwant: %s", req.HTML, want)
+	}
+}
+
+func TestEmbedImagesMissingFile(t *testing.T) {
+	_, _, err := EmbedImages(`<img src="missing.png">`, fstest.MapFS{})
+	if err == nil {
+		t.Error("expected error for missing image")
+	}
+}
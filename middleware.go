@@ -0,0 +1,66 @@
+package sendpigeon
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Call describes a single attempt of an API request as seen by middleware.
+type Call struct {
+	// Service is the API resource the call targets, e.g. "emails" or "contacts".
+	Service string
+	Method  string
+	Path    string
+	// Attempt is the zero-based attempt number; retries reuse the same logical call.
+	Attempt int
+	// Request is the outgoing HTTP request. Middleware may modify its headers.
+	Request *http.Request
+}
+
+// CallResult is the outcome of a single attempt.
+type CallResult struct {
+	// Response is the raw HTTP response, or nil if the request never got one.
+	// Its body has already been read into Body.
+	Response *http.Response
+	Body     []byte
+	// Err is the parsed error for the attempt, or nil on success.
+	Err *Error
+}
+
+// CallHandler performs a single attempt of an API request.
+type CallHandler func(call *Call) *CallResult
+
+// Middleware wraps a CallHandler to observe or modify requests and results.
+//
+// Example:
+//
+//	logging := func(next sendpigeon.CallHandler) sendpigeon.CallHandler {
+//	    return func(call *sendpigeon.Call) *sendpigeon.CallResult {
+//	        result := next(call)
+//	        log.Printf("%s %s attempt=%d err=%v", call.Method, call.Path, call.Attempt, result.Err)
+//	        return result
+//	    }
+//	}
+//	client := sendpigeon.New("sk_live_xxx", &sendpigeon.ClientOptions{
+//	    Middleware: []sendpigeon.Middleware{logging},
+//	})
+type Middleware func(next CallHandler) CallHandler
+
+// chain wraps h with middleware so that the first middleware is outermost.
+func chain(h CallHandler, middleware []Middleware) CallHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			h = middleware[i](h)
+		}
+	}
+	return h
+}
+
+// serviceFromPath derives the service name from an API path.
+func serviceFromPath(path string) string {
+	path = strings.TrimPrefix(path, "/v1/")
+	if i := strings.IndexAny(path, "/?"); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
@@ -0,0 +1,661 @@
+package sendpigeon
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrTemplateRequest is returned by WriteMIME for requests that use a
+// stored template, since the body is only rendered by the API.
+var ErrTemplateRequest = errors.New("sendpigeon: template requests cannot be rendered as MIME")
+
+// mimeHeaderOrder is the order of the headers WriteMIME generates. Custom
+// Headers follow in sorted order.
+var mimeHeaderOrder = []string{"From", "To", "Cc", "Bcc", "Reply-To", "Subject", "Date", "Message-Id", "Mime-Version"}
+
+// ignoredMIMEHeaders are headers ParseMIME does not copy into
+// SendEmailRequest.Headers because they describe transport or structure
+// rather than content.
+var ignoredMIMEHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true,
+	"Subject": true, "Date": true, "Message-Id": true, "Mime-Version": true,
+	"Content-Type": true, "Content-Transfer-Encoding": true, "Content-Disposition": true,
+	"Received": true, "Return-Path": true, "Delivered-To": true, "Dkim-Signature": true,
+	"Authentication-Results": true, "Arc-Seal": true, "Arc-Message-Signature": true,
+	"Arc-Authentication-Results": true, "Received-Spf": true,
+}
+
+// WriteMIME renders the request as an RFC 5322 message with a MIME body:
+// text and HTML become multipart/alternative, inline attachments are
+// grouped with the HTML in multipart/related, and other attachments wrap
+// everything in multipart/mixed. Bcc is included since the output is meant
+// for archives and previews, not delivery. Custom Headers override the
+// generated ones. Header names must be RFC 5322 field names, and no header
+// value may contain CR or LF; otherwise a *ValidationError is returned.
+// Attachments must carry their Content; remote (Path-only) attachments and
+// template requests cannot be rendered.
+//
+// Example:
+//
+//	f, err := os.Create("receipt.eml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer f.Close()
+//	if err := req.WriteMIME(f); err != nil {
+//	    log.Fatal(err)
+//	}
+func (r SendEmailRequest) WriteMIME(w io.Writer) error {
+	if r.TemplateID != "" && r.HTML == "" && r.Text == "" {
+		return ErrTemplateRequest
+	}
+	if err := r.checkMIMEHeaders(); err != nil {
+		return err
+	}
+
+	header := make(textproto.MIMEHeader)
+	setAddressHeader(header, "From", []string{r.From})
+	setAddressHeader(header, "To", r.To)
+	setAddressHeader(header, "Cc", r.CC)
+	setAddressHeader(header, "Bcc", r.BCC)
+	if r.ReplyTo != "" {
+		setAddressListHeader(header, "Reply-To", r.ReplyTo)
+	}
+	if r.Subject != "" {
+		header.Set("Subject", encodeHeader(r.Subject))
+	}
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-Id", "<"+newIdempotencyKey()+"@sendpigeon>")
+	header.Set("Mime-Version", "1.0")
+
+	var custom []string
+	for k, v := range r.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(k)
+		if key == "Mime-Version" || strings.HasPrefix(key, "Content-") {
+			// Structural headers are generated from the body
+			continue
+		}
+		if _, ok := header[key]; !ok {
+			custom = append(custom, key)
+		}
+		header.Set(key, encodeHeader(v))
+	}
+	sort.Strings(custom)
+
+	body, err := r.mimeBody()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	order := append(append([]string(nil), mimeHeaderOrder...), custom...)
+	for _, key := range order {
+		for _, v := range header[key] {
+			writeHeaderField(bw, mimeHeaderName(key), v)
+		}
+	}
+	body.writeHeader(bw)
+	if err := body.writeContent(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ParseMIME reads an RFC 5322 message, such as an .eml file, into a
+// SendEmailRequest. The first text/plain and text/html parts become Text
+// and HTML, other parts become attachments, and headers that are not
+// transport or structural headers are kept in Headers.
+//
+// Example:
+//
+//	f, err := os.Open("legacy/welcome.eml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer f.Close()
+//	req, err := sendpigeon.ParseMIME(f)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	resp, err := client.Send(ctx, req)
+func ParseMIME(r io.Reader) (SendEmailRequest, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return SendEmailRequest{}, err
+	}
+
+	var req SendEmailRequest
+	dec := new(mime.WordDecoder)
+
+	if from := parseAddressHeader(msg.Header, "From"); len(from) > 0 {
+		req.From = from[0]
+	}
+	req.To = parseAddressHeader(msg.Header, "To")
+	req.CC = parseAddressHeader(msg.Header, "Cc")
+	req.BCC = parseAddressHeader(msg.Header, "Bcc")
+	req.ReplyTo = strings.Join(parseAddressHeader(msg.Header, "Reply-To"), ", ")
+	if subject, err := dec.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		req.Subject = subject
+	} else {
+		req.Subject = msg.Header.Get("Subject")
+	}
+
+	for key, values := range msg.Header {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		if ignoredMIMEHeaders[key] || len(values) == 0 {
+			continue
+		}
+		value, err := dec.DecodeHeader(values[0])
+		if err != nil {
+			value = values[0]
+		}
+		if req.Headers == nil {
+			req.Headers = make(map[string]string)
+		}
+		req.Headers[key] = value
+	}
+
+	header := textproto.MIMEHeader(msg.Header)
+	if err := req.readPart(header, msg.Body, false); err != nil {
+		return SendEmailRequest{}, err
+	}
+	return req, nil
+}
+
+// mimePart is a node of the MIME tree written by WriteMIME.
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte      // leaf content, already transfer-encoded
+	parts  []*mimePart // children of a multipart node
+}
+
+// mimeBody builds the MIME tree for the request's bodies and attachments.
+func (r SendEmailRequest) mimeBody() (*mimePart, error) {
+	var alternatives []*mimePart
+	if r.Text != "" {
+		alternatives = append(alternatives, textPart("text/plain", r.Text))
+	}
+	if r.HTML != "" {
+		alternatives = append(alternatives, textPart("text/html", r.HTML))
+	}
+	if len(alternatives) == 0 {
+		alternatives = append(alternatives, textPart("text/plain", ""))
+	}
+
+	var inline, attached []*mimePart
+	for i, a := range r.Attachments {
+		part, err := attachmentPart(a)
+		if err != nil {
+			return nil, fmt.Errorf("attachments[%d]: %w", i, err)
+		}
+		if a.Disposition == AttachmentDispositionInline {
+			inline = append(inline, part)
+		} else {
+			attached = append(attached, part)
+		}
+	}
+
+	// Inline images belong with the HTML alternative
+	if len(inline) > 0 && r.HTML != "" {
+		last := len(alternatives) - 1
+		alternatives[last] = multipartNode("related", append([]*mimePart{alternatives[last]}, inline...))
+	} else {
+		attached = append(inline, attached...)
+	}
+
+	body := alternatives[0]
+	if len(alternatives) > 1 {
+		body = multipartNode("alternative", alternatives)
+	}
+	if len(attached) > 0 {
+		body = multipartNode("mixed", append([]*mimePart{body}, attached...))
+	}
+	return body, nil
+}
+
+func textPart(mediaType, content string) *mimePart {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")))
+	qp.Close()
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"}))
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return &mimePart{header: header, body: buf.Bytes()}
+}
+
+func attachmentPart(a Attachment) (*mimePart, error) {
+	if a.Content == "" && a.Path != "" {
+		return nil, fmt.Errorf("remote attachment %q has no content", a.Filename)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(a.Content), ""))
+	if err != nil {
+		return nil, fmt.Errorf("decode %q: %w", a.Filename, err)
+	}
+
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = detectContentType(a.Filename, data)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	if a.Filename != "" {
+		params["name"] = a.Filename
+	}
+
+	disposition := string(a.Disposition)
+	if disposition == "" {
+		disposition = string(AttachmentDispositionAttachment)
+	}
+	var dispParams map[string]string
+	if a.Filename != "" {
+		dispParams = map[string]string{"filename": a.Filename}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, dispParams))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if a.ContentID != "" {
+		header.Set("Content-Id", "<"+a.ContentID+">")
+	}
+
+	// Wrap base64 at 76 characters per RFC 2045
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	return &mimePart{header: header, body: buf.Bytes()}, nil
+}
+
+func multipartNode(subtype string, parts []*mimePart) *mimePart {
+	boundary := make([]byte, 15)
+	rand.Read(boundary)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype,
+		map[string]string{"boundary": hex.EncodeToString(boundary)}))
+	return &mimePart{header: header, parts: parts}
+}
+
+// writeHeader writes the part's content headers and the blank line that
+// ends the header block.
+func (p *mimePart) writeHeader(w io.Writer) {
+	keys := make([]string, 0, len(p.header))
+	for k := range p.header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeaderField(w, mimeHeaderName(k), p.header.Get(k))
+	}
+	io.WriteString(w, "\r\n")
+}
+
+// writeContent writes the part's body, recursing into multipart children.
+func (p *mimePart) writeContent(w io.Writer) error {
+	if p.parts == nil {
+		_, err := w.Write(p.body)
+		return err
+	}
+
+	_, params, _ := mime.ParseMediaType(p.header.Get("Content-Type"))
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(params["boundary"]); err != nil {
+		return err
+	}
+	for _, child := range p.parts {
+		pw, err := mw.CreatePart(child.header)
+		if err != nil {
+			return err
+		}
+		if err := child.writeContent(pw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// checkMIMEHeaders rejects values that would let a caller inject headers
+// or body content into the rendered message: CR or LF in any header value,
+// and custom header names that are not RFC 5322 field names.
+func (r SendEmailRequest) checkMIMEHeaders() error {
+	var errs fieldErrors
+	line := func(field, v string) {
+		if strings.ContainsAny(v, "\r\n") {
+			errs.add(field, "header", "must not contain CR or LF")
+		}
+	}
+
+	lines := func(field string, values []string) {
+		for i, v := range values {
+			line(fmt.Sprintf("%s[%d]", field, i), v)
+		}
+	}
+
+	line("from", r.From)
+	lines("to", r.To)
+	lines("cc", r.CC)
+	lines("bcc", r.BCC)
+	line("replyTo", r.ReplyTo)
+	line("subject", r.Subject)
+
+	keys := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !validHeaderName(k) {
+			errs.add("headers."+k, "header", fmt.Sprintf("invalid header name %q", k))
+		}
+		line("headers."+k, r.Headers[k])
+	}
+
+	for i, a := range r.Attachments {
+		field := fmt.Sprintf("attachments[%d]", i)
+		line(field+".filename", a.Filename)
+		line(field+".contentType", a.ContentType)
+		line(field+".contentId", a.ContentID)
+	}
+
+	return errs.err()
+}
+
+// validHeaderName reports whether name is an RFC 5322 field name: one or
+// more printable ASCII characters other than colon.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// maxHeaderLine is the line length RFC 5322 recommends headers stay within.
+const maxHeaderLine = 78
+
+// writeHeaderField writes a header field, folding the value at spaces so
+// lines stay within maxHeaderLine characters where possible.
+func writeHeaderField(w io.Writer, name, value string) {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteString(":")
+	lineLen := b.Len()
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && lineLen+1+len(word) > maxHeaderLine {
+			// Fold: the space starts the continuation line
+			b.WriteString("\r\n")
+			lineLen = 0
+		}
+		b.WriteString(" ")
+		b.WriteString(word)
+		lineLen += 1 + len(word)
+	}
+	b.WriteString("\r\n")
+	io.WriteString(w, b.String())
+}
+
+// mimeHeaderName restores the conventional spelling of headers that
+// CanonicalMIMEHeaderKey changes.
+func mimeHeaderName(key string) string {
+	switch key {
+	case "Message-Id":
+		return "Message-ID"
+	case "Mime-Version":
+		return "MIME-Version"
+	case "Content-Id":
+		return "Content-ID"
+	}
+	return key
+}
+
+// setAddressHeader sets key to addrs, encoding display names per RFC 2047.
+func setAddressHeader(header textproto.MIMEHeader, key string, addrs []string) {
+	var out []string
+	for _, s := range addrs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(s); err == nil {
+			s = addr.String()
+		}
+		out = append(out, s)
+	}
+	if len(out) > 0 {
+		header.Set(key, strings.Join(out, ", "))
+	}
+}
+
+// setAddressListHeader sets key from a comma-separated address list.
+// Commas inside quoted display names do not split the list. A list that
+// does not parse is written as is.
+func setAddressListHeader(header textproto.MIMEHeader, key, list string) {
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		if list = strings.TrimSpace(list); list != "" {
+			header.Set(key, list)
+		}
+		return
+	}
+	out := make([]string, len(addrs))
+	for i, addr := range addrs {
+		out[i] = addr.String()
+	}
+	if len(out) > 0 {
+		header.Set(key, strings.Join(out, ", "))
+	}
+}
+
+// encodeHeader RFC 2047 encodes values that are not plain ASCII.
+func encodeHeader(v string) string {
+	if isASCII(v) {
+		return v
+	}
+	return mime.QEncoding.Encode("utf-8", v)
+}
+
+// parseAddressHeader returns the addresses in key, formatted as the API
+// expects them.
+func parseAddressHeader(header mail.Header, key string) []string {
+	if header.Get(key) == "" {
+		return nil
+	}
+	list, err := header.AddressList(key)
+	if err != nil {
+		return []string{header.Get(key)}
+	}
+	out := make([]string, 0, len(list))
+	for _, addr := range list {
+		if s, _, err := formatAddress(*addr); err == nil {
+			out = append(out, s)
+		} else {
+			out = append(out, addr.String())
+		}
+	}
+	return out
+}
+
+// readPart walks a MIME entity, filling in bodies and attachments.
+// inRelated reports whether the part is inside multipart/related, where
+// parts with a Content-ID are inline.
+func (r *SendEmailRequest) readPart(header textproto.MIMEHeader, body io.Reader, inRelated bool) error {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain; charset=us-ascii"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := r.readPart(part.Header, part, inRelated || mediaType == "multipart/related"); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if dec, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = dec
+	}
+	contentID := strings.Trim(header.Get("Content-Id"), "<> ")
+
+	isBody := disposition != "attachment" && filename == "" && contentID == ""
+	switch {
+	case isBody && mediaType == "text/plain" && r.Text == "":
+		r.Text = decodeCharset(data, params["charset"])
+		return nil
+	case isBody && mediaType == "text/html" && r.HTML == "":
+		r.HTML = decodeCharset(data, params["charset"])
+		return nil
+	}
+
+	if filename == "" {
+		filename = fmt.Sprintf("part-%d", len(r.Attachments)+1)
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+	a, err := AttachmentFromReader(filename, bytes.NewReader(data), &AttachmentOptions{ContentType: mediaType})
+	if err != nil {
+		return err
+	}
+	if contentID != "" && (disposition == "inline" || disposition == "" && inRelated) {
+		a = InlineAttachment(a, contentID)
+	} else {
+		a.ContentID = contentID
+	}
+	r.Attachments = append(r.Attachments, a)
+	return nil
+}
+
+// transferDecoder undoes a Content-Transfer-Encoding.
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Cleaner drops the line breaks and spaces base64.NewDecoder rejects.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// decodeCharset converts data to UTF-8. UTF-8, US-ASCII, ISO-8859-1 and
+// Windows-1252 are supported. Invalid UTF-8 sequences, and every non-ASCII
+// byte in any other charset, become utf8.RuneError.
+func decodeCharset(data []byte, charset string) string {
+	var s string
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		s = strings.ToValidUTF8(string(data), string(utf8.RuneError))
+	case "iso-8859-1", "latin1":
+		s = decodeSingleByte(data, nil)
+	case "windows-1252", "cp1252":
+		s = decodeSingleByte(data, &cp1252)
+	default:
+		// Unknown charset: keep ASCII, which every mail charset shares
+		s = decodeSingleByte(data, &unknownHigh)
+	}
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+// decodeSingleByte decodes a single-byte charset that matches Latin-1
+// except, if high is set, for bytes 0x80-0xFF, which high maps instead.
+func decodeSingleByte(data []byte, high *[128]rune) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case high != nil && high[c-0x80] != 0:
+			b.WriteRune(high[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// cp1252 maps the Windows-1252 bytes 0x80-0x9F, which differ from Latin-1.
+// Undefined bytes map to utf8.RuneError.
+var cp1252 = [128]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+// unknownHigh maps every byte 0x80-0xFF to utf8.RuneError.
+var unknownHigh = func() (m [128]rune) {
+	for i := range m {
+		m[i] = utf8.RuneError
+	}
+	return m
+}()
@@ -0,0 +1,219 @@
+package sendpigeon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteMIMERoundTrip(t *testing.T) {
+	logo := InlineAttachment(Attachment{
+		Filename:    "logo.png",
+		Content:     base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\nlogo")),
+		ContentType: "image/png",
+	}, "logo@acme")
+	invoice := Attachment{
+		Filename:    "Rechnung für März.pdf",
+		Content:     base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("%PDF-1.4 "), 20)),
+		ContentType: "application/pdf",
+	}
+	req := SendEmailRequest{
+		From:        `"Acme Support" <support@acme.com>`,
+		To:          []string{"jane@example.com", "Jöhn Doe <john@example.com>"},
+		CC:          []string{"team@acme.com"},
+		BCC:         []string{"audit@acme.com"},
+		ReplyTo:     "help@acme.com, billing@acme.com",
+		Subject:     "Ihre Bestellung – Danke!",
+		Text:        "Thanks for your order.\nSee you soon.",
+		HTML:        `<p>Thanks for your order.</p><img src="cid:logo@acme">`,
+		Attachments: []Attachment{logo, invoice},
+		Headers:     map[string]string{"X-Campaign": "spring", "Content-Type": "ignored"},
+	}
+
+	var buf bytes.Buffer
+	if err := req.WriteMIME(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw := buf.String()
+	for _, want := range []string{
+		"MIME-Version: 1.0\r\n",
+		"Subject: =?utf-8?q?",
+		"X-Campaign: spring\r\n",
+		"Content-Type: multipart/mixed;",
+		"Content-Type: multipart/alternative;",
+		"Content-Type: multipart/related;",
+		"Content-Id: <logo@acme>",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+	if strings.Contains(raw, "ignored") {
+		t.Error("expected custom Content-Type header to be dropped")
+	}
+
+	got, err := ParseMIME(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseMIME: %v", err)
+	}
+	if got.From != req.From {
+		t.Errorf("From = %q, want %q", got.From, req.From)
+	}
+	if len(got.To) != 2 || got.To[0] != "jane@example.com" || !strings.Contains(got.To[1], "john@example.com") {
+		t.Errorf("unexpected To: %v", got.To)
+	}
+	if len(got.CC) != 1 || len(got.BCC) != 1 {
+		t.Errorf("unexpected CC/BCC: %v %v", got.CC, got.BCC)
+	}
+	if got.ReplyTo != req.ReplyTo {
+		t.Errorf("ReplyTo = %q, want %q", got.ReplyTo, req.ReplyTo)
+	}
+	if got.Subject != req.Subject {
+		t.Errorf("Subject = %q, want %q", got.Subject, req.Subject)
+	}
+	if got.Text != req.Text || got.HTML != req.HTML {
+		t.Errorf("unexpected bodies: %q %q", got.Text, got.HTML)
+	}
+	if got.Headers["X-Campaign"] != "spring" || len(got.Headers) != 1 {
+		t.Errorf("unexpected headers: %v", got.Headers)
+	}
+
+	if len(got.Attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(got.Attachments))
+	}
+	if a := got.Attachments[0]; a.Disposition != AttachmentDispositionInline || a.ContentID != "logo@acme" || a.Content != logo.Content {
+		t.Errorf("unexpected inline attachment: %+v", a)
+	}
+	if a := got.Attachments[1]; a.Filename != invoice.Filename || a.Content != invoice.Content || a.ContentType != "application/pdf" {
+		t.Errorf("unexpected attachment: %+v", a)
+	}
+}
+
+func TestWriteMIMETemplateRequest(t *testing.T) {
+	req := SendEmailRequest{To: []string{"user@example.com"}, TemplateID: "tmpl_123"}
+	if err := req.WriteMIME(&bytes.Buffer{}); !errors.Is(err, ErrTemplateRequest) {
+		t.Errorf("expected ErrTemplateRequest, got %v", err)
+	}
+}
+
+func TestWriteMIMEHeaderInjection(t *testing.T) {
+	req := SendEmailRequest{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Hi\r\nBcc: victim@example.com",
+		Text:    "Hello",
+		Headers: map[string]string{
+			"X-Campaign":  "spring\nX-Injected: 1",
+			"Bad Name":    "value",
+			"X-Ticket-Id": "42",
+		},
+	}
+	err := req.WriteMIME(&bytes.Buffer{})
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	want := []string{"subject", "headers.Bad Name", "headers.X-Campaign"}
+	if len(ve.Fields) != len(want) {
+		t.Fatalf("expected %d field errors, got %v", len(want), ve.Fields)
+	}
+	for i, f := range ve.Fields {
+		if f.Field != want[i] || f.Rule != "header" {
+			t.Errorf("field error %d: got %s/%s, want %s/header", i, f.Field, f.Rule, want[i])
+		}
+	}
+}
+
+func TestWriteMIMEReplyToQuotedName(t *testing.T) {
+	req := SendEmailRequest{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		ReplyTo: `"Doe, Jane" <jane@example.com>, support@example.com`,
+		Subject: "Hi",
+		Text:    "Hello",
+	}
+	var buf bytes.Buffer
+	if err := req.WriteMIME(&buf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseMIME(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ReplyTo != req.ReplyTo {
+		t.Errorf("reply-to: got %q, want %q", parsed.ReplyTo, req.ReplyTo)
+	}
+}
+
+func TestWriteMIMEFoldsLongHeaders(t *testing.T) {
+	subject := strings.Repeat("word ", 40)
+	req := SendEmailRequest{
+		From:    "sender@example.com",
+		To:      []string{"user@example.com"},
+		Subject: strings.TrimSpace(subject),
+		Text:    "Hello",
+	}
+	var buf bytes.Buffer
+	if err := req.WriteMIME(&buf); err != nil {
+		t.Fatal(err)
+	}
+	head, _, _ := strings.Cut(buf.String(), "\r\n\r\n")
+	for _, line := range strings.Split(head, "\r\n") {
+		if len(line) > maxHeaderLine {
+			t.Errorf("header line longer than %d: %q", maxHeaderLine, line)
+		}
+	}
+
+	parsed, err := ParseMIME(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Subject != req.Subject {
+		t.Errorf("subject after folding: got %q, want %q", parsed.Subject, req.Subject)
+	}
+}
+
+func TestParseMIMESinglePart(t *testing.T) {
+	raw := "From: sender@example.com\r\n" +
+		"To: user@example.com\r\n" +
+		"Subject: =?iso-8859-1?q?Caf=E9?=\r\n" +
+		"Received: from mx.example.com\r\n" +
+		"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"Caf=E9 au lait\r\n"
+
+	req, err := ParseMIME(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Subject != "Café" || req.Text != "Café au lait\n" {
+		t.Errorf("unexpected subject/text: %q %q", req.Subject, req.Text)
+	}
+	if req.Headers != nil {
+		t.Errorf("expected transport headers to be dropped, got %v", req.Headers)
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    string
+		want    string
+	}{
+		{"utf-8", "caf\xc3\xa9\r\n", "café\n"},
+		{"utf-8", "bad \xff byte", "bad � byte"},
+		{"iso-8859-1", "caf\xe9 \x93", "café \u0093"},
+		{"windows-1252", "\x93quoted\x94 \x80 caf\xe9", "“quoted” € café"},
+		{"Windows-1252", "\x81", "�"},
+		{"koi8-r", "ok \xc1\xc2", "ok ��"},
+	}
+	for _, tt := range tests {
+		if got := decodeCharset([]byte(tt.data), tt.charset); got != tt.want {
+			t.Errorf("decodeCharset(%q, %s) = %q, want %q", tt.data, tt.charset, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 10
+	defaultOutboxLease        = time.Minute
+)
+
+// ErrOutboxMessageNotFound is returned when an outbox message does not exist.
+var ErrOutboxMessageNotFound = errors.New("sendpigeon: outbox message not found")
+
+// OutboxStatus is the delivery state of an outbox message.
+type OutboxStatus string
+
+const (
+	// OutboxStatusPending messages are waiting to be sent.
+	OutboxStatusPending OutboxStatus = "pending"
+	// OutboxStatusSending messages are claimed by a worker until their lease expires.
+	OutboxStatusSending OutboxStatus = "sending"
+	// OutboxStatusSent messages were accepted by the API.
+	OutboxStatusSent OutboxStatus = "sent"
+	// OutboxStatusDead messages failed permanently or ran out of attempts.
+	OutboxStatusDead OutboxStatus = "dead"
+)
+
+// OutboxMessage is an email persisted in an OutboxStore.
+type OutboxMessage struct {
+	ID      string           `json:"id"`
+	Request SendEmailRequest `json:"request"`
+	// IdempotencyKey is sent with every attempt, so a message re-sent after
+	// a crash is not delivered twice.
+	IdempotencyKey string       `json:"idempotencyKey"`
+	Status         OutboxStatus `json:"status"`
+	// Attempts is the number of times the message was sent.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// EmailID is the ID returned by the API once the message is sent.
+	EmailID       string    `json:"emailId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	// ClaimedUntil is the end of the current worker's lease while Status
+	// is OutboxStatusSending.
+	ClaimedUntil time.Time `json:"claimedUntil,omitzero"`
+}
+
+// due reports whether m can be claimed at now: it is pending and its next
+// attempt is due, or its previous worker's lease has expired.
+func (m *OutboxMessage) due(now time.Time) bool {
+	switch m.Status {
+	case OutboxStatusPending:
+		return !m.NextAttemptAt.After(now)
+	case OutboxStatusSending:
+		return !m.ClaimedUntil.After(now)
+	}
+	return false
+}
+
+// OutboxStore persists outbox messages. Implementations must be safe for
+// concurrent use, and Claim must never hand the same due message to two
+// callers.
+type OutboxStore interface {
+	// Save inserts or replaces a message.
+	Save(ctx context.Context, msg *OutboxMessage) error
+	// Get returns the message with the given ID, or ErrOutboxMessageNotFound.
+	Get(ctx context.Context, id string) (*OutboxMessage, error)
+	// Claim marks up to limit due messages as OutboxStatusSending until
+	// now+lease and returns them, oldest first.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	// List returns the messages with the given status, or all messages if
+	// status is empty, oldest first.
+	List(ctx context.Context, status OutboxStatus) ([]*OutboxMessage, error)
+	// Delete removes a message. Deleting a missing message is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// OutboxOptions configures an Outbox.
+type OutboxOptions struct {
+	// PollInterval is how often Run looks for due messages. Defaults to 1s.
+	PollInterval time.Duration
+	// BatchSize is the number of messages claimed at a time. Defaults to 10.
+	BatchSize int
+	// Lease is how long a claimed message is reserved for one worker. If
+	// the worker dies, the message is picked up again once the lease
+	// expires. Defaults to 1m.
+	Lease time.Duration
+	// RetryPolicy decides which failures are retried, how many attempts a
+	// message gets before it is dead-lettered, and how long to wait between
+	// attempts. Defaults to 5 attempts with backoff from 1s up to 5m.
+	RetryPolicy RetryPolicy
+	// OnError is called with store errors encountered by Run.
+	OnError func(error)
+}
+
+// Outbox durably queues emails and delivers them in the background.
+// Enqueue persists a request, with an idempotency key, before anything is
+// sent; Run drains due messages through Client.Send, retrying failures and
+// dead-lettering messages that cannot be delivered. Because every attempt
+// reuses the message's idempotency key, a message re-sent after a crash is
+// not delivered twice. Messages are sent one by one rather than with
+// SendBatch, which takes a single idempotency key per batch.
+//
+// Example:
+//
+//	store, err := sendpigeon.NewFileOutboxStore("/var/lib/myapp/outbox")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	outbox := sendpigeon.NewOutbox(client, store, nil)
+//	go outbox.Run(ctx)
+//
+//	msg, err := outbox.Enqueue(ctx, sendpigeon.SendEmailRequest{
+//	    To:      []string{"user@example.com"},
+//	    Subject: "Welcome!",
+//	    HTML:    "<p>Thanks for signing up!</p>",
+//	})
+type Outbox struct {
+	client       *Client
+	store        OutboxStore
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	retry        RetryPolicy
+	onError      func(error)
+	wake         chan struct{}
+	now          func() time.Time
+}
+
+// NewOutbox returns an Outbox that stores messages in store and sends
+// them through client.
+func NewOutbox(client *Client, store OutboxStore, opts *OutboxOptions) *Outbox {
+	o := &Outbox{
+		client:       client,
+		store:        store,
+		pollInterval: defaultOutboxPollInterval,
+		batchSize:    defaultOutboxBatchSize,
+		lease:        defaultOutboxLease,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+	}
+	if opts != nil {
+		if opts.PollInterval > 0 {
+			o.pollInterval = opts.PollInterval
+		}
+		if opts.BatchSize > 0 {
+			o.batchSize = opts.BatchSize
+		}
+		if opts.Lease > 0 {
+			o.lease = opts.Lease
+		}
+		o.retry = opts.RetryPolicy
+		o.onError = opts.OnError
+	}
+	if o.retry == nil {
+		o.retry = &DefaultRetryPolicy{MaxRetries: 4, BaseDelay: time.Second, MaxDelay: 5 * time.Minute}
+	}
+	return o
+}
+
+// Enqueue validates req and stores it for delivery. The request's
+// IdempotencyKey is kept if set; otherwise the message ID is used.
+func (o *Outbox) Enqueue(ctx context.Context, req SendEmailRequest) (*OutboxMessage, error) {
+	if err := o.client.http.validate(req.Validate); err != nil {
+		return nil, err
+	}
+
+	now := o.now()
+	msg := &OutboxMessage{
+		ID:             newIdempotencyKey(),
+		Request:        req,
+		IdempotencyKey: req.IdempotencyKey,
+		Status:         OutboxStatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		NextAttemptAt:  now,
+	}
+	if msg.IdempotencyKey == "" {
+		msg.IdempotencyKey = msg.ID
+	}
+	if err := o.store.Save(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	// Let Run pick the message up without waiting for the next poll
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return msg, nil
+}
+
+// Get returns the message with the given ID.
+func (o *Outbox) Get(ctx context.Context, id string) (*OutboxMessage, error) {
+	return o.store.Get(ctx, id)
+}
+
+// List returns the messages with the given status, or all messages if
+// status is empty.
+func (o *Outbox) List(ctx context.Context, status OutboxStatus) ([]*OutboxMessage, error) {
+	return o.store.List(ctx, status)
+}
+
+// Requeue moves a dead-lettered message back to pending with a fresh
+// attempt budget.
+func (o *Outbox) Requeue(ctx context.Context, id string) error {
+	msg, err := o.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if msg.Status != OutboxStatusDead {
+		return nil
+	}
+	now := o.now()
+	msg.Status = OutboxStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = now
+	msg.UpdatedAt = now
+	return o.store.Save(ctx, msg)
+}
+
+// Run delivers due messages until ctx is done, polling every
+// PollInterval and immediately after Enqueue. It returns ctx's error.
+func (o *Outbox) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Drain(ctx); err != nil && ctx.Err() == nil && o.onError != nil {
+			o.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Drain sends every message that is currently due and returns how many
+// were processed. Failed sends are rescheduled or dead-lettered rather than
+// reported; the error is only set if the store fails or ctx is done.
+func (o *Outbox) Drain(ctx context.Context) (int, error) {
+	processed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return processed, contextError(err)
+		}
+		msgs, err := o.store.Claim(ctx, o.now(), o.batchSize, o.lease)
+		if err != nil {
+			return processed, err
+		}
+		if len(msgs) == 0 {
+			return processed, nil
+		}
+		for _, msg := range msgs {
+			if err := o.deliver(ctx, msg); err != nil {
+				return processed, err
+			}
+			processed++
+		}
+	}
+}
+
+// deliver sends one claimed message and records the outcome.
+func (o *Outbox) deliver(ctx context.Context, msg *OutboxMessage) error {
+	req := msg.Request
+	req.IdempotencyKey = msg.IdempotencyKey
+	resp, err := o.client.Send(ctx, req)
+
+	// Record the outcome even if ctx has just been cancelled
+	saveCtx := context.WithoutCancel(ctx)
+	now := o.now()
+	msg.UpdatedAt = now
+	msg.ClaimedUntil = time.Time{}
+
+	if err != nil && ctx.Err() != nil {
+		// Interrupted, not failed: release the claim without using an attempt
+		msg.Status = OutboxStatusPending
+		if saveErr := o.store.Save(saveCtx, msg); saveErr != nil {
+			return saveErr
+		}
+		return contextError(ctx.Err())
+	}
+
+	msg.Attempts++
+	if err == nil {
+		msg.Status = OutboxStatusSent
+		msg.EmailID = resp.ID
+		msg.LastError = ""
+		return o.store.Save(saveCtx, msg)
+	}
+	msg.LastError = err.Error()
+
+	var apiErr *Error
+	retryable := errors.As(err, &apiErr) && o.retry.Retryable(apiErr)
+	if !retryable || msg.Attempts >= o.retry.MaxAttempts() {
+		msg.Status = OutboxStatusDead
+		return o.store.Save(saveCtx, msg)
+	}
+
+	var retryAfter time.Duration
+	if apiErr.Header != nil {
+		retryAfter = parseRetryAfter(apiErr.Header.Get("Retry-After"))
+	}
+	msg.Status = OutboxStatusPending
+	msg.NextAttemptAt = now.Add(o.retry.Backoff(msg.Attempts-1, retryAfter))
+	return o.store.Save(saveCtx, msg)
+}
@@ -0,0 +1,286 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryOutboxStore is an OutboxStore that keeps messages in memory. It is
+// useful for tests and for processes that only need in-flight retries, not
+// durability across restarts.
+type MemoryOutboxStore struct {
+	mu       sync.Mutex
+	messages map[string]OutboxMessage
+}
+
+// NewMemoryOutboxStore returns an empty MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{messages: make(map[string]OutboxMessage)}
+}
+
+// Save implements OutboxStore.
+func (s *MemoryOutboxStore) Save(ctx context.Context, msg *OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[msg.ID] = *msg
+	return nil
+}
+
+// Get implements OutboxStore.
+func (s *MemoryOutboxStore) Get(ctx context.Context, id string) (*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[id]
+	if !ok {
+		return nil, ErrOutboxMessageNotFound
+	}
+	return &msg, nil
+}
+
+// Claim implements OutboxStore.
+func (s *MemoryOutboxStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*OutboxMessage
+	for _, msg := range s.messages {
+		if msg.due(now) {
+			due = append(due, &msg)
+		}
+	}
+	due = oldestFirst(due, limit)
+	for _, msg := range due {
+		claim(msg, now, lease)
+		s.messages[msg.ID] = *msg
+	}
+	return due, nil
+}
+
+// List implements OutboxStore.
+func (s *MemoryOutboxStore) List(ctx context.Context, status OutboxStatus) ([]*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*OutboxMessage
+	for _, msg := range s.messages {
+		if status == "" || msg.Status == status {
+			out = append(out, &msg)
+		}
+	}
+	return oldestFirst(out, 0), nil
+}
+
+// Delete implements OutboxStore.
+func (s *MemoryOutboxStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, id)
+	return nil
+}
+
+// FileOutboxStore is an OutboxStore that keeps each message as a JSON file
+// in a directory, so queued emails survive process restarts. Files are
+// replaced atomically. A directory must only be used by one process at a
+// time; use a database-backed OutboxStore to share an outbox between
+// processes.
+type FileOutboxStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileOutboxStore returns a FileOutboxStore using dir, creating it if
+// needed.
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileOutboxStore{dir: dir}, nil
+}
+
+// Save implements OutboxStore.
+func (s *FileOutboxStore) Save(ctx context.Context, msg *OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(msg)
+}
+
+// Get implements OutboxStore.
+func (s *FileOutboxStore) Get(ctx context.Context, id string) (*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	return readOutboxFile(path)
+}
+
+// Claim implements OutboxStore.
+func (s *FileOutboxStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var due []*OutboxMessage
+	for _, msg := range all {
+		if msg.due(now) {
+			due = append(due, msg)
+		}
+	}
+	due = oldestFirst(due, limit)
+	for _, msg := range due {
+		claim(msg, now, lease)
+		if err := s.write(msg); err != nil {
+			return nil, err
+		}
+	}
+	return due, nil
+}
+
+// List implements OutboxStore.
+func (s *FileOutboxStore) List(ctx context.Context, status OutboxStatus) ([]*OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var out []*OutboxMessage
+	for _, msg := range all {
+		if status == "" || msg.Status == status {
+			out = append(out, msg)
+		}
+	}
+	return oldestFirst(out, 0), nil
+}
+
+// Delete implements OutboxStore.
+func (s *FileOutboxStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file for id, rejecting IDs that would escape the directory.
+func (s *FileOutboxStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("sendpigeon: invalid outbox message ID %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// write stores msg via a temporary file and rename so a crash never leaves
+// a partially written message behind.
+func (s *FileOutboxStore) write(msg *OutboxMessage) error {
+	path, err := s.path(msg.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileOutboxStore) readAll() ([]*OutboxMessage, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []*OutboxMessage
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		msg, err := readOutboxFile(filepath.Join(s.dir, name))
+		if errors.Is(err, ErrOutboxMessageNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+func readOutboxFile(path string) (*OutboxMessage, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msg OutboxMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("sendpigeon: corrupt outbox message %s: %w", filepath.Base(path), err)
+	}
+	return &msg, nil
+}
+
+// claim reserves msg for one worker until now+lease.
+func claim(msg *OutboxMessage, now time.Time, lease time.Duration) {
+	msg.Status = OutboxStatusSending
+	msg.ClaimedUntil = now.Add(lease)
+	msg.UpdatedAt = now
+}
+
+// oldestFirst sorts msgs by creation time and keeps at most limit of them.
+// A limit of zero keeps all.
+func oldestFirst(msgs []*OutboxMessage, limit int) []*OutboxMessage {
+	sort.Slice(msgs, func(i, j int) bool {
+		if !msgs[i].CreatedAt.Equal(msgs[j].CreatedAt) {
+			return msgs[i].CreatedAt.Before(msgs[j].CreatedAt)
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs
+}
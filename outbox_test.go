@@ -0,0 +1,154 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutboxDrain(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys = make(map[string][]string) // subject -> idempotency keys seen
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body SendEmailRequest
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		keys[body.Subject] = append(keys[body.Subject], r.Header.Get("Idempotency-Key"))
+		attempt := len(keys[body.Subject])
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case body.Subject == "flaky" && attempt == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"code":"unavailable","message":"try again"}}`))
+		case body.Subject == "rejected":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":{"code":"invalid_recipient","message":"mailbox does not exist"}}`))
+		default:
+			w.Write([]byte(`{"id":"email_` + body.Subject + `","status":"pending"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL, MaxRetries: 0})
+	outbox := NewOutbox(client, NewMemoryOutboxStore(), &OutboxOptions{
+		RetryPolicy: &DefaultRetryPolicy{MaxRetries: 2, BaseDelay: time.Second, DisableJitter: true},
+	})
+	// Drive scheduling with a fake clock so send latency cannot make the
+	// retry due early or late
+	now := time.Now()
+	outbox.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	enqueue := func(subject string) *OutboxMessage {
+		msg, err := outbox.Enqueue(ctx, SendEmailRequest{To: []string{"user@example.com"}, Subject: subject, HTML: "<p>Hi</p>"})
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		return msg
+	}
+	ok, flaky, rejected := enqueue("ok"), enqueue("flaky"), enqueue("rejected")
+
+	if _, err := outbox.Enqueue(ctx, SendEmailRequest{Subject: "no recipient"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected invalid request to be rejected, got %v", err)
+	}
+
+	if n, err := outbox.Drain(ctx); err != nil || n != 3 {
+		t.Fatalf("Drain = %d, %v; want 3, nil", n, err)
+	}
+	now = now.Add(time.Second)
+	if n, err := outbox.Drain(ctx); err != nil || n != 1 {
+		t.Fatalf("second Drain = %d, %v; want 1, nil", n, err)
+	}
+
+	check := func(msg *OutboxMessage, status OutboxStatus, attempts int) *OutboxMessage {
+		t.Helper()
+		got, err := outbox.Get(ctx, msg.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Status != status || got.Attempts != attempts {
+			t.Errorf("%s: status %s after %d attempts, want %s after %d", msg.Request.Subject, got.Status, got.Attempts, status, attempts)
+		}
+		return got
+	}
+	if got := check(ok, OutboxStatusSent, 1); got.EmailID != "email_ok" {
+		t.Errorf("expected EmailID email_ok, got %q", got.EmailID)
+	}
+	check(flaky, OutboxStatusSent, 2)
+	if got := check(rejected, OutboxStatusDead, 1); got.LastError == "" {
+		t.Error("expected dead message to record its last error")
+	}
+
+	if k := keys["flaky"]; len(k) != 2 || k[0] == "" || k[0] != k[1] {
+		t.Errorf("expected retries to reuse one idempotency key, got %v", k)
+	}
+
+	dead, err := outbox.List(ctx, OutboxStatusDead)
+	if err != nil || len(dead) != 1 || dead[0].ID != rejected.ID {
+		t.Errorf("unexpected dead letters: %v, %v", dead, err)
+	}
+	if err := outbox.Requeue(ctx, rejected.ID); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	check(rejected, OutboxStatusPending, 0)
+}
+
+func TestFileOutboxStoreRecoversExpiredClaims(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := time.Now()
+
+	store, err := NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutboxStore: %v", err)
+	}
+	msg := &OutboxMessage{
+		ID:             "msg_1",
+		Request:        SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hello"},
+		IdempotencyKey: "key_1",
+		Status:         OutboxStatusPending,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	}
+	if err := store.Save(ctx, msg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	claimed, err := store.Claim(ctx, now, 10, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Claim = %v, %v", claimed, err)
+	}
+	if again, _ := store.Claim(ctx, now, 10, time.Minute); len(again) != 0 {
+		t.Error("expected a claimed message not to be claimed twice")
+	}
+
+	// A new process finds the message once the crashed worker's lease expires
+	reopened, err := NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutboxStore: %v", err)
+	}
+	recovered, err := reopened.Claim(ctx, now.Add(2*time.Minute), 10, time.Minute)
+	if err != nil || len(recovered) != 1 {
+		t.Fatalf("Claim after lease = %v, %v", recovered, err)
+	}
+	if got := recovered[0]; got.IdempotencyKey != "key_1" || got.Request.Subject != "Hello" {
+		t.Errorf("unexpected recovered message: %+v", got)
+	}
+
+	if err := reopened.Delete(ctx, "msg_1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := reopened.Get(ctx, "msg_1"); !errors.Is(err, ErrOutboxMessageNotFound) {
+		t.Errorf("expected ErrOutboxMessageNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IterOptions controls auto-paginating iterators such as ContactsService.All.
+type IterOptions struct {
+	// MaxItems stops iteration after this many items. Zero means no limit.
+	MaxItems int
+	// Prefetch fetches the next page while the current one is being consumed.
+	Prefetch bool
+}
+
+// values returns the pagination query parameters.
+func (o *ListOptions) values() url.Values {
+	params := url.Values{}
+	if o == nil {
+		return params
+	}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		params.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.Cursor != "" {
+		params.Set("cursor", o.Cursor)
+	}
+	return params
+}
+
+func (o *ListContactsOptions) listOptions() *ListOptions {
+	if o == nil {
+		return nil
+	}
+	return &ListOptions{Limit: o.Limit, Offset: o.Offset, Cursor: o.Cursor}
+}
+
+func (o *ListContactsOptions) values() url.Values {
+	params := o.listOptions().values()
+	if o == nil {
+		return params
+	}
+	if len(o.Tags) > 0 {
+		params.Set("tags", strings.Join(o.Tags, ","))
+	}
+	if o.Status != "" {
+		params.Set("status", o.Status)
+	}
+	if o.Search != "" {
+		params.Set("search", o.Search)
+	}
+	return params
+}
+
+func (o *ListBroadcastsOptions) listOptions() *ListOptions {
+	if o == nil {
+		return nil
+	}
+	return &ListOptions{Limit: o.Limit, Offset: o.Offset, Cursor: o.Cursor}
+}
+
+func (o *ListBroadcastsOptions) values() url.Values {
+	params := o.listOptions().values()
+	if o != nil && o.Status != "" {
+		params.Set("status", o.Status)
+	}
+	return params
+}
+
+func (o *ListRecipientsOptions) listOptions() *ListOptions {
+	if o == nil {
+		return nil
+	}
+	return &ListOptions{Limit: o.Limit, Offset: o.Offset, Cursor: o.Cursor}
+}
+
+func (o *ListRecipientsOptions) values() url.Values {
+	params := o.listOptions().values()
+	if o != nil && o.Status != "" {
+		params.Set("status", o.Status)
+	}
+	return params
+}
+
+func (o *ListEmailsOptions) listOptions() *ListOptions {
+	if o == nil {
+		return nil
+	}
+	return &ListOptions{Limit: o.Limit, Cursor: o.Cursor}
+}
+
+func (o *ListEmailsOptions) values() url.Values {
+	params := o.listOptions().values()
+	if o == nil {
+		return params
+	}
+	if o.Status != "" {
+		params.Set("status", string(o.Status))
+	}
+	if o.Tag != "" {
+		params.Set("tag", o.Tag)
+	}
+	for k, v := range o.Metadata {
+		params.Set("metadata["+k+"]", v)
+	}
+	if o.To != "" {
+		params.Set("to", o.To)
+	}
+	if o.From != "" {
+		params.Set("from", o.From)
+	}
+	setTime(params, "created_after", o.CreatedAfter)
+	setTime(params, "created_before", o.CreatedBefore)
+	setTime(params, "sent_after", o.SentAfter)
+	setTime(params, "sent_before", o.SentBefore)
+	return params
+}
+
+// setTime sets key to t in RFC 3339 format unless t is zero.
+func setTime(params url.Values, key string, t time.Time) {
+	if !t.IsZero() {
+		params.Set(key, t.UTC().Format(time.RFC3339))
+	}
+}
+
+// withQuery appends encoded params to path.
+func withQuery(path string, params url.Values) string {
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + params.Encode()
+}
+
+// getList fetches and decodes a single page of a list endpoint.
+func getList[T any](ctx context.Context, c *httpClient, path string) (*ListResponse[T], error) {
+	body, err := c.Get(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ListResponse[T]
+	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
+	}
+
+	return &resp, nil
+}
+
+// page is one page of results as seen by paginate.
+type page[T any] struct {
+	items   []T
+	next    string // cursor of the next page, if the endpoint uses cursors
+	total   int    // total number of items, or -1 if unknown
+	offsets bool   // whether the endpoint accepts an offset parameter
+}
+
+// pageFunc fetches the page at cursor, or at offset when cursor is empty.
+type pageFunc[T any] func(ctx context.Context, cursor string, offset int) (page[T], error)
+
+// listPages returns a pageFunc for an endpoint returning ListResponse[T].
+// params holds the caller's filters; pagination parameters are replaced.
+func listPages[T any](c *httpClient, path string, params url.Values) pageFunc[T] {
+	return func(ctx context.Context, cursor string, offset int) (page[T], error) {
+		q := url.Values{}
+		for k, v := range params {
+			q[k] = v
+		}
+		q.Del("cursor")
+		q.Del("offset")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		} else if offset > 0 {
+			q.Set("offset", strconv.Itoa(offset))
+		}
+
+		resp, err := getList[T](ctx, c, withQuery(path, q))
+		if err != nil {
+			return page[T]{}, err
+		}
+		return page[T]{items: resp.Data, next: resp.Cursor.Next, total: -1, offsets: true}, nil
+	}
+}
+
+// cursorPages is like listPages for endpoints that only paginate by
+// cursor and ignore offset.
+func cursorPages[T any](c *httpClient, path string, params url.Values) pageFunc[T] {
+	fetch := listPages[T](c, path, params)
+	return func(ctx context.Context, cursor string, _ int) (page[T], error) {
+		p, err := fetch(ctx, cursor, 0)
+		p.offsets = false
+		return p, err
+	}
+}
+
+// paginate returns an iterator over every item of a list endpoint, starting
+// at start. It follows cursors when the server returns them. Otherwise, on
+// endpoints that accept offsets, it advances the offset until the reported
+// total is reached, a page comes back shorter than Limit or, when Limit is
+// not set, a page comes back empty; on other endpoints a page without a
+// next cursor is the last.
+func paginate[T any](ctx context.Context, start *ListOptions, fetch pageFunc[T], opts *IterOptions) iter.Seq2[T, error] {
+	var limit, offset int
+	var cursor string
+	if start != nil {
+		limit, offset, cursor = start.Limit, start.Offset, start.Cursor
+	}
+	var maxItems int
+	prefetch := false
+	if opts != nil {
+		maxItems = opts.MaxItems
+		prefetch = opts.Prefetch
+	}
+
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		load := func(cursor string, offset int) func() (page[T], error) {
+			if !prefetch {
+				return func() (page[T], error) {
+					if err := ctx.Err(); err != nil {
+						return page[T]{}, contextError(err)
+					}
+					return fetch(ctx, cursor, offset)
+				}
+			}
+
+			type result struct {
+				page page[T]
+				err  error
+			}
+			ch := make(chan result, 1)
+			go func() {
+				p, err := fetch(ctx, cursor, offset)
+				ch <- result{p, err}
+			}()
+			return func() (page[T], error) {
+				select {
+				case r := <-ch:
+					return r.page, r.err
+				case <-ctx.Done():
+					return page[T]{}, contextError(ctx.Err())
+				}
+			}
+		}
+
+		var zero T
+		yielded := 0
+		next := load(cursor, offset)
+		for {
+			p, err := next()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			// Work out the next page before yielding so it can be prefetched.
+			offset += len(p.items)
+			more := len(p.items) > 0
+			switch {
+			case !more:
+			case maxItems > 0 && yielded+len(p.items) >= maxItems:
+				more = false
+			case p.next != "":
+				more = p.next != cursor
+				cursor = p.next
+			case p.total >= 0:
+				more = offset < p.total
+				cursor = ""
+			case cursor != "" || !p.offsets:
+				// A cursor-paginated endpoint without a next cursor is
+				// done; asking for an offset would repeat the same page
+				more = false
+			case limit > 0:
+				more = len(p.items) >= limit
+			default:
+				// The server picks the page size, so only an empty page
+				// marks the end
+			}
+			if more {
+				if p.next != "" {
+					next = load(cursor, 0)
+				} else {
+					next = load("", offset)
+				}
+			}
+
+			for _, item := range p.items {
+				if maxItems > 0 && yielded >= maxItems {
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+				yielded++
+			}
+
+			if !more {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestContactsAllFollowsCursor(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		if r.URL.Query().Get("status") != "ACTIVE" {
+			t.Errorf("expected status filter on every page, got %q", r.URL.RawQuery)
+		}
+		pages := map[string]map[string]interface{}{
+			"":   {"data": []map[string]string{{"id": "c1"}, {"id": "c2"}}, "cursor": map[string]string{"next": "p2"}},
+			"p2": {"data": []map[string]string{{"id": "c3"}}, "cursor": map[string]string{"next": "p3"}},
+			"p3": {"data": []map[string]string{{"id": "c4"}}},
+		}
+		json.NewEncoder(w).Encode(pages[r.URL.Query().Get("cursor")])
+	}))
+	defer server.Close()
+
+	for _, prefetch := range []bool{false, true} {
+		requests = nil
+		client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+		var ids []string
+		for contact, err := range client.Contacts.All(context.Background(), &ListContactsOptions{Status: "ACTIVE"}, &IterOptions{Prefetch: prefetch}) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids = append(ids, contact.ID)
+		}
+		if fmt.Sprint(ids) != "[c1 c2 c3 c4]" {
+			t.Errorf("prefetch=%v: unexpected ids %v", prefetch, ids)
+		}
+		if len(requests) != 3 {
+			t.Errorf("prefetch=%v: expected 3 requests, got %d", prefetch, len(requests))
+		}
+	}
+}
+
+func TestTemplatesAllFollowsOffset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var data []map[string]string
+		for i := offset; i < offset+2 && i < 5; i++ {
+			data = append(data, map[string]string{"id": fmt.Sprintf("t%d", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	var ids []string
+	for tmpl, err := range client.Templates.All(context.Background(), &ListOptions{Limit: 2}, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, tmpl.ID)
+	}
+	if fmt.Sprint(ids) != "[t0 t1 t2 t3 t4]" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+}
+
+func TestTemplatesAllWithoutLimit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Has("limit") {
+			t.Errorf("unexpected limit in %q", r.URL.RawQuery)
+		}
+		// The server uses its own page size of 2
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		data := []map[string]string{}
+		for i := offset; i < offset+2 && i < 5; i++ {
+			data = append(data, map[string]string{"id": fmt.Sprintf("t%d", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	var ids []string
+	for tmpl, err := range client.Templates.All(context.Background(), nil, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, tmpl.ID)
+	}
+	if fmt.Sprint(ids) != "[t0 t1 t2 t3 t4]" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+	if requests != 4 {
+		t.Errorf("expected 4 requests, got %d", requests)
+	}
+}
+
+func TestCursorOnlyWithoutNextCursor(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 3 {
+			t.Errorf("endless pagination: %d requests", requests)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": []string{}})
+			return
+		}
+		// Ignores offset, so every request returns the same page
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]string{{"id": "t0"}, {"id": "t1"}},
+		})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	fetch := cursorPages[Template](client.Templates.http, "/v1/templates", nil)
+	var ids []string
+	for tmpl, err := range paginate(context.Background(), nil, fetch, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, tmpl.ID)
+	}
+	if fmt.Sprint(ids) != "[t0 t1]" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestAllMaxItemsAndErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("cursor") == "bad" {
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":   []map[string]string{{"id": "b1"}, {"id": "b2"}, {"id": "b3"}},
+			"cursor": map[string]string{"next": "bad"},
+		})
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+
+	count := 0
+	for _, err := range client.Broadcasts.All(context.Background(), nil, &IterOptions{MaxItems: 2}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		count++
+	}
+	if count != 2 || requests != 1 {
+		t.Errorf("expected 2 items from 1 request, got %d items from %d requests", count, requests)
+	}
+
+	var lastErr error
+	for _, err := range client.Broadcasts.All(context.Background(), nil, nil) {
+		lastErr = err
+	}
+	if lastErr == nil {
+		t.Error("expected error from second page")
+	}
+}
@@ -0,0 +1,505 @@
+package sendpigeon
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HTMLToText derives a readable plain-text body from an HTML email.
+// Scripts, styles and the document head are dropped, headings are
+// underlined, list items are bulleted or numbered, table cells are joined
+// with " | " one row per line, and links are kept as numbered footnotes
+// listed at the end.
+//
+// Example:
+//
+//	text := sendpigeon.HTMLToText(`<h1>Welcome</h1><p>Read the <a href="https://acme.com/docs">docs</a>.</p>`)
+//	// Welcome
+//	// =======
+//	//
+//	// Read the docs [1].
+//	//
+//	// [1] https://acme.com/docs
+func HTMLToText(htmlBody string) string {
+	w := &textWriter{footnoteIndex: make(map[string]int)}
+	w.convert(htmlBody)
+	return w.String()
+}
+
+// withAutoText fills in req.Text from req.HTML when AutoText is enabled.
+func (c *httpClient) withAutoText(req SendEmailRequest) SendEmailRequest {
+	if c.autoText && req.Text == "" && req.HTML != "" {
+		req.Text = HTMLToText(req.HTML)
+	}
+	return req
+}
+
+// skipContentTags are elements whose content is never rendered.
+var skipContentTags = map[string]bool{
+	"head": true, "title": true, "script": true, "style": true,
+	"noscript": true, "template": true, "svg": true,
+}
+
+// paragraphTags are block elements separated by a blank line.
+var paragraphTags = map[string]bool{
+	"p": true, "blockquote": true, "pre": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// lineTags are block elements that start on a new line.
+var lineTags = map[string]bool{
+	"div": true, "section": true, "article": true, "header": true,
+	"footer": true, "main": true, "nav": true, "aside": true, "address": true,
+	"center": true, "tr": true, "dl": true, "dt": true, "dd": true,
+	"figure": true, "figcaption": true, "form": true,
+}
+
+// list tracks an open <ul> or <ol>.
+type list struct {
+	ordered bool
+	next    int
+}
+
+// link tracks an open <a>.
+type link struct {
+	href  string
+	start int
+}
+
+// textWriter accumulates plain text, collapsing whitespace and deferring
+// line breaks until more text arrives.
+type textWriter struct {
+	buf strings.Builder
+
+	pendingSpace bool
+	pendingBreak int
+	trailing     int  // newlines at the end of buf
+	lineStarted  bool // whether the current line has text
+
+	prefixes []string // per-level line prefixes ("  " for lists, "> " for quotes)
+	lists    []list
+	links    []link
+	pre      int
+	cell     int // cells written in the current table row
+
+	footnotes     []string
+	footnoteIndex map[string]int
+}
+
+func (w *textWriter) convert(s string) {
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			j := strings.IndexByte(s[i:], '<')
+			if j < 0 {
+				j = len(s) - i
+			}
+			w.text(html.UnescapeString(s[i : i+j]))
+			i += j
+			continue
+		}
+
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return
+			}
+			i += 4 + end + 3
+			continue
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+			continue
+		}
+
+		name, attrs, closing, n := parseTag(rest)
+		if n == 0 {
+			// A stray '<' is literal text
+			w.text("<")
+			i++
+			continue
+		}
+		i += n
+
+		if !closing && skipContentTags[name] {
+			if strings.HasSuffix(rest[:n], "/>") {
+				// Self-closing, so there is no content to skip
+				continue
+			}
+			end := indexFold(s[i:], "</"+name)
+			if end < 0 {
+				// Unclosed: drop only the tag itself
+				continue
+			}
+			i += end
+			if gt := strings.IndexByte(s[i:], '>'); gt >= 0 {
+				i += gt + 1
+			} else {
+				return
+			}
+			continue
+		}
+
+		if closing {
+			w.closeTag(name)
+		} else {
+			w.openTag(name, attrs)
+		}
+	}
+}
+
+func (w *textWriter) openTag(name string, attrs map[string]string) {
+	switch {
+	case name == "br":
+		w.pendingBreak++
+		w.pendingSpace = false
+	case name == "hr":
+		w.blockBreak(2)
+		w.raw(strings.Repeat("-", 40))
+		w.blockBreak(2)
+	case name == "ul" || name == "ol":
+		if len(w.lists) == 0 {
+			w.blockBreak(2)
+		} else {
+			w.blockBreak(1)
+		}
+		l := list{ordered: name == "ol", next: 1}
+		if start, err := strconv.Atoi(strings.TrimSpace(attrs["start"])); err == nil {
+			l.next = start
+		}
+		w.lists = append(w.lists, l)
+		w.prefixes = append(w.prefixes, "  ")
+	case name == "li":
+		w.blockBreak(1)
+		bullet := "- "
+		if n := len(w.lists); n > 0 && w.lists[n-1].ordered {
+			bullet = fmt.Sprintf("%d. ", w.lists[n-1].next)
+			w.lists[n-1].next++
+		}
+		w.write(bullet, 1)
+	case name == "blockquote":
+		w.blockBreak(2)
+		w.prefixes = append(w.prefixes, "> ")
+	case name == "pre":
+		w.blockBreak(2)
+		w.pre++
+	case name == "tr":
+		w.blockBreak(1)
+		w.cell = 0
+	case name == "td" || name == "th":
+		if w.cell > 0 {
+			w.pendingSpace = false
+			w.raw(" | ")
+		}
+		w.cell++
+	case name == "a":
+		w.links = append(w.links, link{href: strings.TrimSpace(attrs["href"]), start: w.buf.Len()})
+	case name == "img":
+		if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+			w.text(alt)
+		}
+	case paragraphTags[name]:
+		w.blockBreak(2)
+	case lineTags[name]:
+		w.blockBreak(1)
+	}
+}
+
+func (w *textWriter) closeTag(name string) {
+	switch {
+	case name == "ul" || name == "ol":
+		if len(w.lists) > 0 {
+			w.lists = w.lists[:len(w.lists)-1]
+			w.popPrefix()
+		}
+		if len(w.lists) == 0 {
+			w.blockBreak(2)
+		} else {
+			w.blockBreak(1)
+		}
+	case name == "li":
+		w.blockBreak(1)
+	case name == "blockquote":
+		w.popPrefix()
+		w.blockBreak(2)
+	case name == "pre":
+		if w.pre > 0 {
+			w.pre--
+		}
+		w.blockBreak(2)
+	case name == "a":
+		if n := len(w.links); n > 0 {
+			l := w.links[n-1]
+			w.links = w.links[:n-1]
+			w.closeLink(l)
+		}
+	case name == "h1" || name == "h2" || name == "h3" || name == "h4" || name == "h5" || name == "h6":
+		w.underline(name)
+		w.blockBreak(2)
+	case paragraphTags[name]:
+		w.blockBreak(2)
+	case lineTags[name]:
+		w.blockBreak(1)
+	}
+}
+
+// closeLink writes the footnote marker for l, or the URL itself when the
+// link has no text.
+func (w *textWriter) closeLink(l link) {
+	href := l.href
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+		return
+	}
+
+	text := ""
+	if l.start <= w.buf.Len() {
+		text = strings.TrimSpace(w.buf.String()[l.start:])
+	}
+	switch {
+	case text == "":
+		w.text(strings.TrimPrefix(href, "mailto:"))
+		return
+	case text == href, strings.HasPrefix(lower, "mailto:") && text == href[len("mailto:"):]:
+		return
+	}
+
+	n, ok := w.footnoteIndex[href]
+	if !ok {
+		w.footnotes = append(w.footnotes, href)
+		n = len(w.footnotes)
+		w.footnoteIndex[href] = n
+	}
+	w.raw(fmt.Sprintf(" [%d]", n))
+}
+
+// underline draws a rule under the heading on the current line.
+func (w *textWriter) underline(tag string) {
+	if !w.lineStarted || w.pendingBreak > 0 {
+		return
+	}
+	s := w.buf.String()
+	line := s[strings.LastIndexByte(s, '\n')+1:]
+	width := utf8.RuneCountInString(line) - utf8.RuneCountInString(strings.Join(w.prefixes, ""))
+	if width <= 0 {
+		return
+	}
+	rule := "-"
+	if tag == "h1" {
+		rule = "="
+	}
+	w.pendingSpace = false
+	w.pendingBreak = 1
+	w.raw(strings.Repeat(rule, width))
+}
+
+// text writes character data, collapsing whitespace outside <pre>.
+func (w *textWriter) text(s string) {
+	if w.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.pendingBreak++
+			}
+			if line != "" {
+				w.raw(line)
+			}
+		}
+		return
+	}
+
+	start := -1
+	for i, r := range s {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			w.word(s[start:i])
+			start = -1
+		}
+		w.pendingSpace = true
+	}
+	if start >= 0 {
+		w.word(s[start:])
+	}
+}
+
+// word writes a run of non-space text, preceded by a pending space.
+func (w *textWriter) word(s string) {
+	if w.pendingSpace && w.lineStarted && w.pendingBreak == 0 {
+		w.raw(" ")
+	}
+	w.pendingSpace = false
+	w.raw(s)
+}
+
+// raw writes s verbatim after flushing any pending line break.
+func (w *textWriter) raw(s string) {
+	w.write(s, 0)
+}
+
+// write is raw with the innermost omit line prefixes left off, so list
+// bullets sit one level out from their continuation lines.
+func (w *textWriter) write(s string, omit int) {
+	if w.pendingBreak > 0 && w.buf.Len() > 0 {
+		for ; w.trailing < w.pendingBreak; w.trailing++ {
+			w.buf.WriteByte('\n')
+		}
+		w.lineStarted = false
+	}
+	w.pendingBreak = 0
+	w.pendingSpace = false
+	w.startLine(omit)
+	w.buf.WriteString(s)
+	w.trailing = 0
+	w.lineStarted = true
+}
+
+// startLine writes the line prefix, leaving off the innermost omit levels.
+func (w *textWriter) startLine(omit int) {
+	if w.lineStarted {
+		return
+	}
+	n := len(w.prefixes) - omit
+	if n < 0 {
+		n = 0
+	}
+	w.buf.WriteString(strings.Join(w.prefixes[:n], ""))
+	w.lineStarted = true
+}
+
+// blockBreak ensures at least n line breaks before the next text.
+func (w *textWriter) blockBreak(n int) {
+	if n > w.pendingBreak {
+		w.pendingBreak = n
+	}
+	w.pendingSpace = false
+}
+
+func (w *textWriter) popPrefix() {
+	if len(w.prefixes) > 0 {
+		w.prefixes = w.prefixes[:len(w.prefixes)-1]
+	}
+}
+
+// String returns the text with trailing spaces trimmed and link footnotes
+// appended.
+func (w *textWriter) String() string {
+	lines := strings.Split(w.buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	out := strings.Trim(strings.Join(lines, "\n"), "\n")
+
+	if len(w.footnotes) > 0 {
+		var b strings.Builder
+		b.WriteString(out)
+		b.WriteString("\n")
+		for i, href := range w.footnotes {
+			fmt.Fprintf(&b, "\n[%d] %s", i+1, href)
+		}
+		out = b.String()
+	}
+	return out
+}
+
+// parseTag parses the tag at the start of s. It returns the lower-cased
+// name, attributes, whether it is a closing tag, and the number of bytes
+// consumed, which is zero if s does not start with a tag.
+func parseTag(s string) (name string, attrs map[string]string, closing bool, n int) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || start < i && s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	if i == start {
+		return "", nil, false, 0
+	}
+	name = strings.ToLower(s[start:i])
+
+	attrs = make(map[string]string)
+	for i < len(s) {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return name, attrs, closing, i + 1
+		}
+
+		keyStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[keyStart:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			if key != "" {
+				attrs[key] = ""
+			}
+			continue
+		}
+		i++
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+
+		var value string
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			quote := s[i]
+			end := strings.IndexByte(s[i+1:], quote)
+			if end < 0 {
+				break
+			}
+			value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			valueStart := i
+			for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			value = s[valueStart:i]
+		}
+		if key != "" {
+			attrs[key] = html.UnescapeString(value)
+		}
+	}
+	// Unterminated tag
+	return "", nil, false, 0
+}
+
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// indexFold is a case-insensitive strings.Index for an ASCII substr.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,123 @@
+package sendpigeon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "strips head, styles and scripts",
+			html: `<html><head><title>Receipt</title><style>p { color: red }</style></head>` +
+				`<body><p>Hello   <b>Jane</b>,</p><script>track()</script><p>Thanks &amp; bye</p></body></html>`,
+			want: "Hello Jane,\n\nThanks & bye",
+		},
+		{
+			name: "links become footnotes",
+			html: `<p>Read the <a href="https://acme.com/docs">docs</a>, the <a href="https://acme.com/faq">FAQ</a>` +
+				` or the <a href="https://acme.com/docs">docs again</a>. Visit <a href="https://acme.com">https://acme.com</a>` +
+				` or <a href="mailto:help@acme.com">help@acme.com</a>.</p>`,
+			want: "Read the docs [1], the FAQ [2] or the docs again [1]. Visit https://acme.com or help@acme.com.\n\n" +
+				"[1] https://acme.com/docs\n[2] https://acme.com/faq",
+		},
+		{
+			name: "headings",
+			html: `<h1>Welcome</h1><p>Intro</p><h2>Your order</h2>`,
+			want: "Welcome\n=======\n\nIntro\n\nYour order\n----------",
+		},
+		{
+			name: "lists",
+			html: `<ul><li>One</li><li>Two<ul><li>Nested</li></ul></li></ul><ol start="3"><li>Three</li><li>Four</li></ol>`,
+			want: "- One\n- Two\n  - Nested\n\n3. Three\n4. Four",
+		},
+		{
+			name: "table rows",
+			html: `<table><tr><th>Item</th><th>Qty</th></tr><tr><td>Widget</td><td>2</td></tr></table>`,
+			want: "Item | Qty\nWidget | 2",
+		},
+		{
+			name: "line breaks, quotes and preformatted text",
+			html: "<p>a<br>b</p><blockquote>quoted</blockquote><pre>x\n  y</pre>",
+			want: "a\nb\n\n> quoted\n\nx\n  y",
+		},
+		{
+			name: "self-closing svg",
+			html: `<p>Logo <svg viewBox="0 0 1 1"/> Acme</p><p>Next</p>`,
+			want: "Logo Acme\n\nNext",
+		},
+		{
+			name: "unclosed script",
+			html: `<p>Before</p><script src="x.js"><p>After</p>`,
+			want: "Before\n\nAfter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.html); got != tt.want {
+				t.Errorf("HTMLToText() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendAutoText(t *testing.T) {
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text   string             `json:"text"`
+			Emails []SendEmailRequest `json:"emails"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path == "/v1/emails/batch" {
+			for _, email := range body.Emails {
+				texts = append(texts, email.Text)
+			}
+		} else {
+			texts = append(texts, body.Text)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"email_123","status":"pending","data":[]}`))
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL, AutoText: true})
+	ctx := context.Background()
+
+	if _, err := client.Send(ctx, SendEmailRequest{
+		To:      []string{"user@example.com"},
+		Subject: "Hello",
+		HTML:    "<p>Hi <b>there</b>!</p>",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	emails := []SendEmailRequest{
+		{To: []string{"a@example.com"}, Subject: "Hello", HTML: "<p>Hi A</p>"},
+		{To: []string{"b@example.com"}, Subject: "Hello", HTML: "<p>Hi B</p>", Text: "Custom"},
+	}
+	if _, err := client.SendBatch(ctx, emails); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"Hi there!", "Hi A", "Custom"}
+	if len(texts) != len(want) {
+		t.Fatalf("expected %d text bodies, got %v", len(want), texts)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("text[%d] = %q, want %q", i, texts[i], want[i])
+		}
+	}
+	if emails[0].Text != "" {
+		t.Error("expected SendBatch not to modify the caller's requests")
+	}
+}
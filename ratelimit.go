@@ -0,0 +1,271 @@
+package sendpigeon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitGroup identifies a set of endpoints that share a rate limit.
+type RateLimitGroup string
+
+const (
+	// RateLimitGroupSend covers single sends (POST /v1/emails).
+	RateLimitGroupSend RateLimitGroup = "send"
+	// RateLimitGroupBatch covers batch sends (POST /v1/emails/batch).
+	RateLimitGroupBatch RateLimitGroup = "batch"
+	// RateLimitGroupContacts covers the Contacts API.
+	RateLimitGroupContacts RateLimitGroup = "contacts"
+	// RateLimitGroupManagement covers every other endpoint.
+	RateLimitGroupManagement RateLimitGroup = "management"
+)
+
+// RateLimit is a token bucket: requests are allowed at Rate per second on
+// average, with bursts of up to Burst. A zero Rate means no client-side
+// limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits configures client-side rate limiting per endpoint group.
+// When set, requests also wait while the API reports an exhausted quota
+// through X-RateLimit-Remaining/X-RateLimit-Reset or Retry-After.
+type RateLimits struct {
+	Send       RateLimit
+	Batch      RateLimit
+	Contacts   RateLimit
+	Management RateLimit
+}
+
+// RateLimitState is a snapshot of an endpoint group's quota.
+type RateLimitState struct {
+	Group RateLimitGroup
+	// Limit, Remaining and Reset are the last values reported by the API.
+	// Remaining is -1 and Reset is zero until the API has reported them.
+	Limit     int
+	Remaining int
+	Reset     time.Time
+	// Tokens is the number of requests the client-side bucket allows right
+	// now. It is -1 when the group has no client-side limit.
+	Tokens float64
+	// BlockedUntil is when requests may resume after the API signalled
+	// that the quota is exhausted. It is zero when not blocked.
+	BlockedUntil time.Time
+}
+
+// rateLimiter holds one bucket per endpoint group. It is shared by every
+// service of a Client.
+type rateLimiter struct {
+	enforce bool
+	buckets map[RateLimitGroup]*bucket
+}
+
+// bucket is a token bucket combined with the quota reported by the API.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	limit        int
+	remaining    int
+	reset        time.Time
+	blockedUntil time.Time
+}
+
+func newRateLimiter(limits *RateLimits) *rateLimiter {
+	var cfg RateLimits
+	if limits != nil {
+		cfg = *limits
+	}
+	return &rateLimiter{
+		enforce: limits != nil,
+		buckets: map[RateLimitGroup]*bucket{
+			RateLimitGroupSend:       newBucket(cfg.Send),
+			RateLimitGroupBatch:      newBucket(cfg.Batch),
+			RateLimitGroupContacts:   newBucket(cfg.Contacts),
+			RateLimitGroupManagement: newBucket(cfg.Management),
+		},
+	}
+}
+
+func newBucket(limit RateLimit) *bucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: limit.Rate, burst: burst, tokens: burst, last: time.Now(), remaining: -1}
+}
+
+// rateLimitGroup classifies a request into its endpoint group.
+func rateLimitGroup(method, path string) RateLimitGroup {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	switch {
+	case method == http.MethodPost && path == "/v1/emails":
+		return RateLimitGroupSend
+	case method == http.MethodPost && path == "/v1/emails/batch":
+		return RateLimitGroupBatch
+	case path == "/v1/contacts" || strings.HasPrefix(path, "/v1/contacts/"):
+		return RateLimitGroupContacts
+	}
+	return RateLimitGroupManagement
+}
+
+// middleware waits for quota before each attempt and records the quota
+// reported in the response. It runs innermost, so retries are limited too.
+func (l *rateLimiter) middleware(next CallHandler) CallHandler {
+	return func(call *Call) *CallResult {
+		b := l.buckets[rateLimitGroup(call.Method, call.Path)]
+
+		if l.enforce {
+			if err := b.wait(call.Request.Context()); err != nil {
+				return &CallResult{Err: err}
+			}
+		}
+
+		result := next(call)
+		if result != nil && result.Response != nil {
+			b.observe(result.Response, time.Now())
+		}
+		return result
+	}
+}
+
+// wait blocks until a request may be made or ctx is done. It fails at once
+// if the wait would outlast ctx's deadline.
+func (b *bucket) wait(ctx context.Context) *Error {
+	delay := b.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		b.cancel()
+		return wrapError(ErrorCodeTimeout, fmt.Sprintf("rate limit wait of %v exceeds context deadline", delay.Round(time.Millisecond)), context.DeadlineExceeded)
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		b.cancel()
+		return contextError(err)
+	}
+	return nil
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var delay time.Duration
+	if b.blockedUntil.After(now) {
+		delay = b.blockedUntil.Sub(now)
+	}
+	if b.rate > 0 {
+		b.refill(now)
+		b.tokens--
+		if b.tokens < 0 {
+			delay = max(delay, time.Duration(-b.tokens/b.rate*float64(time.Second)))
+		}
+	}
+	return delay
+}
+
+// cancel returns a token taken by reserve that was not used.
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate > 0 {
+		b.tokens = min(b.tokens+1, b.burst)
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.burst)
+		b.last = now
+	}
+}
+
+// observe updates the bucket from X-RateLimit-* and Retry-After headers.
+func (b *bucket) observe(resp *http.Response, now time.Time) {
+	h := resp.Header
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Limit")); err == nil {
+		b.limit = v
+	}
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		b.reset = parseRateLimitReset(v, now)
+	}
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		b.remaining = v
+		if b.rate > 0 {
+			// Never assume more quota than the API has left
+			b.refill(now)
+			b.tokens = min(b.tokens, float64(v))
+		}
+		if v <= 0 && b.reset.After(now) {
+			b.blockedUntil = b.reset
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if d := parseRetryAfter(h.Get("Retry-After")); d > 0 {
+			b.blockedUntil = now.Add(d)
+		}
+	}
+}
+
+// parseRateLimitReset interprets X-RateLimit-Reset as a Unix timestamp or,
+// for small values, as seconds from now.
+func parseRateLimitReset(v int64, now time.Time) time.Time {
+	if v > 1e9 {
+		return time.Unix(v, 0)
+	}
+	return now.Add(time.Duration(v) * time.Second)
+}
+
+func (b *bucket) state(group RateLimitGroup, now time.Time) RateLimitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := RateLimitState{
+		Group:     group,
+		Limit:     b.limit,
+		Remaining: b.remaining,
+		Reset:     b.reset,
+		Tokens:    -1,
+	}
+	if b.rate > 0 {
+		b.refill(now)
+		s.Tokens = b.tokens
+	}
+	if b.blockedUntil.After(now) {
+		s.BlockedUntil = b.blockedUntil
+	}
+	return s
+}
+
+// RateLimitState returns the current quota of an endpoint group, so callers
+// can throttle their own work before the API does.
+//
+// Example:
+//
+//	state := client.RateLimitState(sendpigeon.RateLimitGroupSend)
+//	if state.Remaining == 0 {
+//	    time.Sleep(time.Until(state.Reset))
+//	}
+func (c *Client) RateLimitState(group RateLimitGroup) RateLimitState {
+	b, ok := c.http.limiter.buckets[group]
+	if !ok {
+		return RateLimitState{Group: group, Remaining: -1, Tokens: -1}
+	}
+	return b.state(group, time.Now())
+}
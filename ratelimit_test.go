@@ -0,0 +1,126 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRateLimitServer(t *testing.T, headers map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"email_123","status":"pending"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	server := newRateLimitServer(t, nil)
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:    server.URL,
+		RateLimits: &RateLimits{Send: RateLimit{Rate: 20, Burst: 2}},
+	})
+	req := SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hi</p>"}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := client.Send(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Two requests use the burst, the other two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected sends to be throttled, took %v", elapsed)
+	}
+
+	// Other groups are limited independently
+	start = time.Now()
+	if _, err := client.Emails.Get(context.Background(), "email_123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("expected management call not to wait, took %v", elapsed)
+	}
+}
+
+func TestRateLimitRespectsDeadline(t *testing.T) {
+	server := newRateLimitServer(t, nil)
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL:    server.URL,
+		MaxRetries: 0,
+		RateLimits: &RateLimits{Send: RateLimit{Rate: 1, Burst: 1}},
+	})
+	req := SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hi</p>"}
+
+	if _, err := client.Send(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Send(ctx, req)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != ErrorCodeTimeout {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected to fail without waiting, took %v", elapsed)
+	}
+}
+
+func TestRateLimitStateFromHeaders(t *testing.T) {
+	server := newRateLimitServer(t, map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "30",
+	})
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+
+	_, err := client.Send(context.Background(), SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hello", HTML: "<p>Hi</p>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	state := client.RateLimitState(RateLimitGroupSend)
+	if state.Limit != 100 || state.Remaining != 0 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if until := time.Until(state.BlockedUntil); until < 25*time.Second || until > 30*time.Second {
+		t.Errorf("expected group to be blocked for about 30s, got %v", until)
+	}
+	if state.Tokens != -1 {
+		t.Errorf("expected no client-side bucket, got %v tokens", state.Tokens)
+	}
+
+	if other := client.RateLimitState(RateLimitGroupContacts); other.Remaining != -1 || !other.BlockedUntil.IsZero() {
+		t.Errorf("expected contacts group to be unaffected, got %+v", other)
+	}
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         RateLimitGroup
+	}{
+		{http.MethodPost, "/v1/emails", RateLimitGroupSend},
+		{http.MethodPost, "/v1/emails/batch", RateLimitGroupBatch},
+		{http.MethodGet, "/v1/emails/email_123", RateLimitGroupManagement},
+		{http.MethodGet, "/v1/contacts?limit=10", RateLimitGroupContacts},
+		{http.MethodPost, "/v1/contacts/batch", RateLimitGroupContacts},
+		{http.MethodGet, "/v1/templates", RateLimitGroupManagement},
+	}
+	for _, tt := range tests {
+		if got := rateLimitGroup(tt.method, tt.path); got != tt.want {
+			t.Errorf("rateLimitGroup(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package sendpigeon
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultBaseDelay = 100 * time.Millisecond
+	defaultMaxDelay  = 10 * time.Second
+)
+
+var defaultRetryableCodes = []ErrorCode{ErrorCodeNetwork, ErrorCodeAPI}
+
+// RetryPolicy decides whether and when a failed request is retried.
+type RetryPolicy interface {
+	// MaxAttempts returns the maximum number of attempts, including the first.
+	MaxAttempts() int
+	// MaxElapsedTime bounds the total time spent on a call, including
+	// waits between attempts. Zero means no limit.
+	MaxElapsedTime() time.Duration
+	// Retryable reports whether a failed attempt may be retried.
+	Retryable(err *Error) bool
+	// Backoff returns how long to wait before the next attempt. attempt is the
+	// zero-based number of the attempt that just failed; retryAfter is the
+	// server's Retry-After hint, or zero if none was sent.
+	Backoff(attempt int, retryAfter time.Duration) time.Duration
+}
+
+// DefaultRetryPolicy is an exponential backoff policy with full jitter.
+//
+// Zero values fall back to the defaults: 100ms base delay, 10s max delay,
+// retries on network errors, 429 and 5xx responses.
+type DefaultRetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// MaxElapsed bounds the total time spent on a call. Zero means no limit.
+	MaxElapsed time.Duration
+	// RetryableStatuses overrides the HTTP statuses that are retried.
+	RetryableStatuses []int
+	// RetryableCodes overrides the error codes that are retried. API errors
+	// are additionally filtered by RetryableStatuses.
+	RetryableCodes []ErrorCode
+	// DisableJitter uses the plain exponential delay instead of a random
+	// delay between zero and it.
+	DisableJitter bool
+}
+
+// MaxAttempts implements RetryPolicy.
+func (p *DefaultRetryPolicy) MaxAttempts() int {
+	if p.MaxRetries < 0 {
+		return 1
+	}
+	return p.MaxRetries + 1
+}
+
+// MaxElapsedTime implements RetryPolicy.
+func (p *DefaultRetryPolicy) MaxElapsedTime() time.Duration {
+	return p.MaxElapsed
+}
+
+// Retryable implements RetryPolicy.
+func (p *DefaultRetryPolicy) Retryable(err *Error) bool {
+	if err == nil {
+		return false
+	}
+
+	codes := p.RetryableCodes
+	if codes == nil {
+		codes = defaultRetryableCodes
+	}
+	found := false
+	for _, code := range codes {
+		if err.Code == code {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if err.Code != ErrorCodeAPI {
+		return true
+	}
+	if p.RetryableStatuses == nil {
+		return err.Status == 429 || err.Status >= 500
+	}
+	for _, status := range p.RetryableStatuses {
+		if err.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff implements RetryPolicy.
+func (p *DefaultRetryPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	max := p.MaxDelay
+	if max <= 0 {
+		max = defaultMaxDelay
+	}
+
+	backoff := max
+	if attempt < 30 {
+		if d := base << uint(attempt); d > 0 && d < max {
+			backoff = d
+		}
+	}
+
+	if p.DisableJitter {
+		return backoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// parseRetryAfter parses a Retry-After header given either as delay seconds
+// or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
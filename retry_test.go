@@ -0,0 +1,93 @@
+package sendpigeon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryStopsOnContextDeadline(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(503)
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL, MaxRetries: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Emails.Get(ctx, "email_123")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("expected retry to give up before the deadline, took %v", elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryPolicyCustom(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(409)
+	}))
+	defer server.Close()
+
+	client := New("sk_test_xxx", &ClientOptions{
+		BaseURL: server.URL,
+		RetryPolicy: &DefaultRetryPolicy{
+			MaxRetries:        2,
+			BaseDelay:         time.Millisecond,
+			RetryableStatuses: []int{409},
+		},
+	})
+	_, err := client.Emails.Get(context.Background(), "email_123")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDefaultRetryPolicyBackoff(t *testing.T) {
+	p := &DefaultRetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		if d := p.Backoff(attempt, 0); d < 0 || d > time.Second {
+			t.Errorf("attempt %d: backoff %v out of range", attempt, d)
+		}
+	}
+
+	p.DisableJitter = true
+	if d := p.Backoff(2, 0); d != 400*time.Millisecond {
+		t.Errorf("expected 400ms, got %v", d)
+	}
+	if d := p.Backoff(20, 0); d != time.Second {
+		t.Errorf("expected 1s cap, got %v", d)
+	}
+	if d := p.Backoff(0, 3*time.Second); d != 3*time.Second {
+		t.Errorf("expected Retry-After to win, got %v", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("2"); d != 2*time.Second {
+		t.Errorf("expected 2s, got %v", d)
+	}
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d < 8*time.Second || d > 10*time.Second {
+		t.Errorf("expected ~10s from HTTP date, got %v", d)
+	}
+	if d := parseRetryAfter("garbage"); d != 0 {
+		t.Errorf("expected 0, got %v", d)
+	}
+}
@@ -3,6 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
+	"iter"
 	"net/url"
 	"strconv"
 )
@@ -13,36 +14,56 @@ type SuppressionsService struct {
 }
 
 // List lists suppressed email addresses.
-func (s *SuppressionsService) List(ctx context.Context, opts *ListOptions) (*SuppressionListResponse, *Error) {
-	path := "/v1/suppressions"
+func (s *SuppressionsService) List(ctx context.Context, opts *ListOptions) (*SuppressionListResponse, error) {
+	var params url.Values
 	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
+		params = (&ListOptions{Limit: opts.Limit, Offset: opts.Offset}).values()
+	}
+	return s.list(ctx, params)
+}
+
+// All iterates over all suppressed email addresses, fetching pages as needed.
+func (s *SuppressionsService) All(ctx context.Context, opts *ListOptions, iterOpts *IterOptions) iter.Seq2[Suppression, error] {
+	var start *ListOptions
+	var params url.Values
+	if opts != nil {
+		start = &ListOptions{Limit: opts.Limit, Offset: opts.Offset}
+		params = start.values()
+	}
+	fetch := func(ctx context.Context, _ string, offset int) (page[Suppression], error) {
+		q := url.Values{}
+		for k, v := range params {
+			q[k] = v
 		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
+		q.Del("offset")
+		if offset > 0 {
+			q.Set("offset", strconv.Itoa(offset))
 		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
+		resp, err := s.list(ctx, q)
+		if err != nil {
+			return page[Suppression]{}, err
 		}
+		return page[Suppression]{items: resp.Data, total: resp.Total, offsets: true}, nil
 	}
+	return paginate(ctx, start, fetch, iterOpts)
+}
 
-	body, err := s.http.Get(ctx, path, nil)
+func (s *SuppressionsService) list(ctx context.Context, params url.Values) (*SuppressionListResponse, error) {
+	body, err := s.http.Get(ctx, withQuery("/v1/suppressions", params), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp SuppressionListResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Delete removes an email address from the suppression list.
-func (s *SuppressionsService) Delete(ctx context.Context, email string) *Error {
+func (s *SuppressionsService) Delete(ctx context.Context, email string) error {
 	_, err := s.http.Delete(ctx, "/v1/suppressions/"+url.PathEscape(email), nil)
 	return err
 }
@@ -3,8 +3,7 @@ package sendpigeon
 import (
 	"context"
 	"encoding/json"
-	"net/url"
-	"strconv"
+	"iter"
 )
 
 // TemplatesService handles template operations.
@@ -13,7 +12,11 @@ type TemplatesService struct {
 }
 
 // Create creates a new template.
-func (s *TemplatesService) Create(ctx context.Context, req CreateTemplateRequest) (*Template, *Error) {
+func (s *TemplatesService) Create(ctx context.Context, req CreateTemplateRequest) (*Template, error) {
+	if err := s.http.validate(req.Validate); err != nil {
+		return nil, err
+	}
+
 	body, err := s.http.Post(ctx, "/v1/templates", req, nil)
 	if err != nil {
 		return nil, err
@@ -21,14 +24,14 @@ func (s *TemplatesService) Create(ctx context.Context, req CreateTemplateRequest
 
 	var resp Template
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Get retrieves a template by ID.
-func (s *TemplatesService) Get(ctx context.Context, id string) (*Template, *Error) {
+func (s *TemplatesService) Get(ctx context.Context, id string) (*Template, error) {
 	body, err := s.http.Get(ctx, "/v1/templates/"+id, nil)
 	if err != nil {
 		return nil, err
@@ -36,46 +39,24 @@ func (s *TemplatesService) Get(ctx context.Context, id string) (*Template, *Erro
 
 	var resp Template
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // List lists all templates.
-func (s *TemplatesService) List(ctx context.Context, opts *ListOptions) (*ListResponse[Template], *Error) {
-	path := "/v1/templates"
-	if opts != nil {
-		params := url.Values{}
-		if opts.Limit > 0 {
-			params.Set("limit", strconv.Itoa(opts.Limit))
-		}
-		if opts.Offset > 0 {
-			params.Set("offset", strconv.Itoa(opts.Offset))
-		}
-		if opts.Cursor != "" {
-			params.Set("cursor", opts.Cursor)
-		}
-		if len(params) > 0 {
-			path += "?" + params.Encode()
-		}
-	}
-
-	body, err := s.http.Get(ctx, path, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ListResponse[Template]
-	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
-	}
+func (s *TemplatesService) List(ctx context.Context, opts *ListOptions) (*ListResponse[Template], error) {
+	return getList[Template](ctx, s.http, withQuery("/v1/templates", opts.values()))
+}
 
-	return &resp, nil
+// All iterates over all templates, fetching pages as needed.
+func (s *TemplatesService) All(ctx context.Context, opts *ListOptions, iterOpts *IterOptions) iter.Seq2[Template, error] {
+	return paginate(ctx, opts, listPages[Template](s.http, "/v1/templates", opts.values()), iterOpts)
 }
 
 // Update updates a template.
-func (s *TemplatesService) Update(ctx context.Context, id string, req UpdateTemplateRequest) (*Template, *Error) {
+func (s *TemplatesService) Update(ctx context.Context, id string, req UpdateTemplateRequest) (*Template, error) {
 	body, err := s.http.Patch(ctx, "/v1/templates/"+id, req, nil)
 	if err != nil {
 		return nil, err
@@ -83,20 +64,20 @@ func (s *TemplatesService) Update(ctx context.Context, id string, req UpdateTemp
 
 	var resp Template
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Delete deletes a template.
-func (s *TemplatesService) Delete(ctx context.Context, id string) *Error {
+func (s *TemplatesService) Delete(ctx context.Context, id string) error {
 	_, err := s.http.Delete(ctx, "/v1/templates/"+id, nil)
 	return err
 }
 
 // Publish publishes a template.
-func (s *TemplatesService) Publish(ctx context.Context, id string) (*Template, *Error) {
+func (s *TemplatesService) Publish(ctx context.Context, id string) (*Template, error) {
 	body, err := s.http.Post(ctx, "/v1/templates/"+id+"/publish", nil, nil)
 	if err != nil {
 		return nil, err
@@ -104,14 +85,14 @@ func (s *TemplatesService) Publish(ctx context.Context, id string) (*Template, *
 
 	var resp Template
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Unpublish unpublishes a template.
-func (s *TemplatesService) Unpublish(ctx context.Context, id string) (*Template, *Error) {
+func (s *TemplatesService) Unpublish(ctx context.Context, id string) (*Template, error) {
 	body, err := s.http.Post(ctx, "/v1/templates/"+id+"/unpublish", nil, nil)
 	if err != nil {
 		return nil, err
@@ -119,14 +100,14 @@ func (s *TemplatesService) Unpublish(ctx context.Context, id string) (*Template,
 
 	var resp Template
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // Test sends a test email using the template.
-func (s *TemplatesService) Test(ctx context.Context, id string, req TestTemplateRequest) (*TestTemplateResponse, *Error) {
+func (s *TemplatesService) Test(ctx context.Context, id string, req TestTemplateRequest) (*TestTemplateResponse, error) {
 	body, err := s.http.Post(ctx, "/v1/templates/"+id+"/test", req, nil)
 	if err != nil {
 		return nil, err
@@ -134,7 +115,7 @@ func (s *TemplatesService) Test(ctx context.Context, id string, req TestTemplate
 
 	var resp TestTemplateResponse
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
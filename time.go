@@ -0,0 +1,110 @@
+package sendpigeon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Time is a timestamp sent to or returned by the API. It embeds time.Time,
+// so it can be compared, sorted and formatted directly.
+//
+// When decoding, Time accepts RFC 3339 timestamps with or without
+// fractional seconds, timestamps without a zone (taken as UTC), date-only
+// values and Unix timestamps in seconds or milliseconds. Null and empty
+// strings decode to the zero Time, which encodes as null.
+type Time struct {
+	time.Time
+}
+
+// timeLayouts are the string formats Time accepts, tried in order.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+// MarshalJSON encodes t in RFC 3339 format, or null if t is zero.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time.Format(time.RFC3339Nano))
+}
+
+// UnmarshalJSON decodes any of the formats described on Time.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '"' {
+		n, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("sendpigeon: invalid time %s", data)
+		}
+		t.Time = unixTime(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseTime(s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// parseTime parses s in any of timeLayouts. Empty strings are the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			return parsed, nil
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return unixTime(n), nil
+	}
+	return time.Time{}, fmt.Errorf("sendpigeon: invalid time %q", s)
+}
+
+// unixTime interprets n as Unix seconds, or milliseconds when it is too
+// large to be a plausible number of seconds.
+func unixTime(n int64) time.Time {
+	if n > 1e11 || n < -1e11 {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+// ScheduleAt schedules the email for delivery at t.
+func (r *SendEmailRequest) ScheduleAt(t time.Time) {
+	r.ScheduledAt = Time{t}
+}
+
+// ScheduleIn schedules the email for delivery d from now.
+//
+// Example:
+//
+//	req := sendpigeon.SendEmailRequest{
+//	    To:      []string{"user@example.com"},
+//	    Subject: "Reminder",
+//	    HTML:    "<p>Don't forget about tomorrow's meeting!</p>",
+//	}
+//	req.ScheduleIn(24 * time.Hour)
+func (r *SendEmailRequest) ScheduleIn(d time.Duration) {
+	r.ScheduleAt(time.Now().Add(d))
+}
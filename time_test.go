@@ -0,0 +1,88 @@
+package sendpigeon
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeUnmarshalJSON(t *testing.T) {
+	want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{`"2024-01-15T10:30:00Z"`, want},
+		{`"2024-01-15T10:30:00.000Z"`, want},
+		{`"2024-01-15T11:30:00+01:00"`, want},
+		{`"2024-01-15T10:30:00"`, want},
+		{`"2024-01-15 10:30:00"`, want},
+		{`"2024-01-15"`, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{`1705314600`, want},
+		{`1705314600000`, want},
+		{`""`, time.Time{}},
+		{`null`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		var got Time
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	var bad Time
+	if err := json.Unmarshal([]byte(`"next tuesday"`), &bad); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
+
+func TestTimeMarshalJSON(t *testing.T) {
+	req := SendEmailRequest{To: []string{"user@example.com"}}
+	body, _ := json.Marshal(req)
+	if strings.Contains(string(body), "scheduled_at") {
+		t.Errorf("expected zero ScheduledAt to be omitted, got %s", body)
+	}
+
+	req.ScheduleAt(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC))
+	body, _ = json.Marshal(req)
+	if !strings.Contains(string(body), `"scheduled_at":"2024-01-15T10:30:00Z"`) {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	req.ScheduleIn(time.Hour)
+	if d := time.Until(req.ScheduledAt.Time); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected ScheduledAt about an hour from now, got %v", req.ScheduledAt)
+	}
+}
+
+func TestScheduledAtWireNames(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	// The emails resource uses snake_case, broadcasts use camelCase
+	var resp SendEmailResponse
+	if err := json.Unmarshal([]byte(`{"id":"email_1","status":"scheduled","scheduled_at":"2024-01-15T10:30:00Z"}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.ScheduledAt.Equal(at) {
+		t.Errorf("SendEmailResponse.ScheduledAt = %v, want %v", resp.ScheduledAt, at)
+	}
+
+	var b Broadcast
+	if err := json.Unmarshal([]byte(`{"id":"bc_1","status":"SCHEDULED","scheduledAt":"2024-01-15T10:30:00Z"}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if !b.ScheduledAt.Equal(at) {
+		t.Errorf("Broadcast.ScheduledAt = %v, want %v", b.ScheduledAt, at)
+	}
+
+	body, _ := json.Marshal(ScheduleBroadcastRequest{ScheduledAt: Time{at}})
+	if !strings.Contains(string(body), `"scheduledAt":"2024-01-15T10:30:00Z"`) {
+		t.Errorf("unexpected broadcast body: %s", body)
+	}
+}
@@ -11,7 +11,7 @@ type TrackingService struct {
 }
 
 // GetDefaults retrieves organization tracking defaults.
-func (s *TrackingService) GetDefaults(ctx context.Context) (*TrackingDefaults, *Error) {
+func (s *TrackingService) GetDefaults(ctx context.Context) (*TrackingDefaults, error) {
 	body, err := s.http.Get(ctx, "/v1/tracking/defaults", nil)
 	if err != nil {
 		return nil, err
@@ -19,14 +19,14 @@ func (s *TrackingService) GetDefaults(ctx context.Context) (*TrackingDefaults, *
 
 	var resp TrackingDefaults
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
 }
 
 // UpdateDefaults updates organization tracking defaults.
-func (s *TrackingService) UpdateDefaults(ctx context.Context, req UpdateTrackingDefaultsRequest) (*TrackingDefaults, *Error) {
+func (s *TrackingService) UpdateDefaults(ctx context.Context, req UpdateTrackingDefaultsRequest) (*TrackingDefaults, error) {
 	body, err := s.http.Patch(ctx, "/v1/tracking/defaults", req, nil)
 	if err != nil {
 		return nil, err
@@ -34,7 +34,7 @@ func (s *TrackingService) UpdateDefaults(ctx context.Context, req UpdateTracking
 
 	var resp TrackingDefaults
 	if jsonErr := json.Unmarshal(body, &resp); jsonErr != nil {
-		return nil, NewError(ErrorCodeNetwork, "failed to parse response")
+		return nil, wrapError(ErrorCodeNetwork, "failed to parse response", jsonErr)
 	}
 
 	return &resp, nil
@@ -1,5 +1,7 @@
 package sendpigeon
 
+import "time"
+
 // EmailStatus represents the status of an email.
 type EmailStatus string
 
@@ -14,6 +16,15 @@ const (
 	EmailStatusFailed     EmailStatus = "failed"
 )
 
+// Terminal reports whether no further status changes are expected.
+func (s EmailStatus) Terminal() bool {
+	switch s {
+	case EmailStatusDelivered, EmailStatusBounced, EmailStatusComplained, EmailStatusFailed, EmailStatusCancelled:
+		return true
+	}
+	return false
+}
+
 // DomainStatus represents the status of a domain.
 type DomainStatus string
 
@@ -63,12 +74,27 @@ type TrackingOptions struct {
 	Clicks *bool `json:"clicks,omitempty"`
 }
 
+// AttachmentDisposition controls how an attachment is presented.
+type AttachmentDisposition string
+
+const (
+	AttachmentDispositionAttachment AttachmentDisposition = "attachment"
+	AttachmentDispositionInline     AttachmentDisposition = "inline"
+)
+
 // Attachment represents an email attachment.
 type Attachment struct {
 	Filename    string `json:"filename"`
 	Content     string `json:"content,omitempty"`
 	Path        string `json:"path,omitempty"`
 	ContentType string `json:"contentType,omitempty"`
+	// ContentID lets HTML reference an inline attachment as "cid:<ContentID>".
+	ContentID   string                `json:"contentId,omitempty"`
+	Disposition AttachmentDisposition `json:"disposition,omitempty"`
+	// Size is the raw size in bytes, set by the Attachment constructors.
+	Size int64 `json:"-"`
+	// Checksum is the hex SHA-256 of the raw content, set by the Attachment constructors.
+	Checksum string `json:"-"`
 }
 
 // AttachmentMeta represents attachment metadata returned from API.
@@ -94,7 +120,7 @@ type SendEmailRequest struct {
 	Tags           []string          `json:"tags,omitempty"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
 	Headers        map[string]string `json:"headers,omitempty"`
-	ScheduledAt    string            `json:"scheduled_at,omitempty"`
+	ScheduledAt    Time              `json:"scheduled_at,omitzero"`
 	Tracking       *TrackingOptions  `json:"tracking,omitempty"`
 	IdempotencyKey string            `json:"-"` // Sent as header
 }
@@ -103,47 +129,209 @@ type SendEmailRequest struct {
 type SendEmailResponse struct {
 	ID          string      `json:"id"`
 	Status      EmailStatus `json:"status"`
-	ScheduledAt string      `json:"scheduled_at,omitempty"`
+	ScheduledAt Time        `json:"scheduled_at,omitzero"`
 	Suppressed  []string    `json:"suppressed,omitempty"`
 	Warnings    []string    `json:"warnings,omitempty"`
+	// IdempotencyKey is the key sent with the request, if any.
+	IdempotencyKey string `json:"-"`
+}
+
+// BatchEmailStatus represents the outcome of one email in a batch.
+type BatchEmailStatus string
+
+const (
+	BatchEmailStatusSent       BatchEmailStatus = "sent"
+	BatchEmailStatusScheduled  BatchEmailStatus = "scheduled"
+	BatchEmailStatusSuppressed BatchEmailStatus = "suppressed"
+	BatchEmailStatusFailed     BatchEmailStatus = "failed"
+)
+
+// BatchError describes why an email in a batch failed.
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
 }
 
 // BatchEmailResult represents the result for a single email in a batch.
 type BatchEmailResult struct {
-	Index      int                    `json:"index"`
-	Status     string                 `json:"status"`
-	ID         string                 `json:"id,omitempty"`
-	Suppressed []string               `json:"suppressed,omitempty"`
-	Warnings   []string               `json:"warnings,omitempty"`
-	Error      map[string]interface{} `json:"error,omitempty"`
+	Index      int              `json:"index"`
+	Status     BatchEmailStatus `json:"status"`
+	ID         string           `json:"id,omitempty"`
+	Suppressed []string         `json:"suppressed,omitempty"`
+	Warnings   []string         `json:"warnings,omitempty"`
+	Error      *BatchError      `json:"error,omitempty"`
+}
+
+// Failed reports whether the email was not accepted.
+func (r BatchEmailResult) Failed() bool {
+	return r.Error != nil || r.Status == BatchEmailStatusFailed
+}
+
+// BatchSummary counts the outcomes of a batch.
+type BatchSummary struct {
+	Total      int `json:"total"`
+	Sent       int `json:"sent"`
+	Failed     int `json:"failed"`
+	Suppressed int `json:"suppressed"`
 }
 
 // SendBatchResponse represents the response from sending batch emails.
 type SendBatchResponse struct {
-	Data    []BatchEmailResult     `json:"data"`
-	Summary map[string]interface{} `json:"summary"`
+	Data    []BatchEmailResult `json:"data"`
+	Summary BatchSummary       `json:"summary"`
+	// IdempotencyKey is the key sent with the request, if any.
+	IdempotencyKey string `json:"-"`
+}
+
+// Failed returns the results of the emails that were not accepted.
+func (r *SendBatchResponse) Failed() []BatchEmailResult {
+	var out []BatchEmailResult
+	for _, result := range r.Data {
+		if result.Failed() {
+			out = append(out, result)
+		}
+	}
+	return out
+}
+
+// Succeeded returns the results of the emails that were accepted.
+func (r *SendBatchResponse) Succeeded() []BatchEmailResult {
+	var out []BatchEmailResult
+	for _, result := range r.Data {
+		if !result.Failed() {
+			out = append(out, result)
+		}
+	}
+	return out
+}
+
+// ToRequests returns the requests from original, the slice passed to
+// SendBatch, whose emails failed, ready to be resubmitted.
+//
+// Example:
+//
+//	resp, err := client.SendBatch(ctx, emails)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if retry := resp.ToRequests(emails); len(retry) > 0 {
+//	    resp, err = client.SendBatch(ctx, retry)
+//	}
+func (r *SendBatchResponse) ToRequests(original []SendEmailRequest) []SendEmailRequest {
+	var out []SendEmailRequest
+	for _, result := range r.Failed() {
+		if result.Index >= 0 && result.Index < len(original) {
+			out = append(out, original[result.Index])
+		}
+	}
+	return out
 }
 
 // EmailDetail represents detailed email information.
 type EmailDetail struct {
-	ID            string           `json:"id"`
-	FromAddress   string           `json:"from_address"`
-	ToAddress     string           `json:"to_address"`
-	Subject       string           `json:"subject"`
-	Status        EmailStatus      `json:"status"`
-	CreatedAt     string           `json:"created_at"`
-	CCAddress     string           `json:"cc_address,omitempty"`
-	BCCAddress    string           `json:"bcc_address,omitempty"`
-	Tags          []string         `json:"tags,omitempty"`
+	ID            string                 `json:"id"`
+	FromAddress   string                 `json:"from_address"`
+	ToAddress     string                 `json:"to_address"`
+	Subject       string                 `json:"subject"`
+	Status        EmailStatus            `json:"status"`
+	CreatedAt     Time                   `json:"created_at"`
+	CCAddress     string                 `json:"cc_address,omitempty"`
+	BCCAddress    string                 `json:"bcc_address,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
-	SentAt        string           `json:"sent_at,omitempty"`
-	DeliveredAt   string           `json:"delivered_at,omitempty"`
-	BouncedAt     string           `json:"bounced_at,omitempty"`
-	ComplainedAt  string           `json:"complained_at,omitempty"`
-	BounceType    string           `json:"bounce_type,omitempty"`
-	ComplaintType string           `json:"complaint_type,omitempty"`
-	Attachments   []AttachmentMeta `json:"attachments,omitempty"`
-	HasBody       bool             `json:"has_body"`
+	SentAt        Time                   `json:"sent_at,omitzero"`
+	DeliveredAt   Time                   `json:"delivered_at,omitzero"`
+	BouncedAt     Time                   `json:"bounced_at,omitzero"`
+	ComplainedAt  Time                   `json:"complained_at,omitzero"`
+	BounceType    string                 `json:"bounce_type,omitempty"`
+	ComplaintType string                 `json:"complaint_type,omitempty"`
+	Attachments   []AttachmentMeta       `json:"attachments,omitempty"`
+	HasBody       bool                   `json:"has_body"`
+}
+
+// EmailEventType represents the type of an event in an email's timeline.
+type EmailEventType string
+
+const (
+	EmailEventQueued     EmailEventType = "queued"
+	EmailEventSent       EmailEventType = "sent"
+	EmailEventDelivered  EmailEventType = "delivered"
+	EmailEventDeferred   EmailEventType = "deferred"
+	EmailEventBounced    EmailEventType = "bounced"
+	EmailEventOpened     EmailEventType = "opened"
+	EmailEventClicked    EmailEventType = "clicked"
+	EmailEventComplained EmailEventType = "complained"
+)
+
+// EmailEvent represents a single event in an email's timeline.
+type EmailEvent struct {
+	Type      EmailEventType `json:"type"`
+	Timestamp Time           `json:"timestamp"`
+	// Present for deferred and bounced events
+	BounceType     string `json:"bounce_type,omitempty"`
+	DiagnosticCode string `json:"diagnostic_code,omitempty"`
+	// Present for opened and clicked events
+	UserAgent string `json:"user_agent,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	// Present for clicked events
+	LinkURL   string `json:"link_url,omitempty"`
+	LinkIndex *int   `json:"link_index,omitempty"`
+	// Present for complained events
+	ComplaintType string `json:"complaint_type,omitempty"`
+}
+
+// ListEmailsOptions represents options for listing emails.
+type ListEmailsOptions struct {
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	// Status filters by delivery status.
+	Status EmailStatus `json:"status,omitempty"`
+	// Tag filters emails sent with this tag.
+	Tag string `json:"tag,omitempty"`
+	// Metadata filters emails whose metadata contains all of these key/value pairs.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// To filters by recipient. A value starting with "@" matches a whole domain.
+	To string `json:"to,omitempty"`
+	// From filters by sender address.
+	From string `json:"from,omitempty"`
+	// Created and sent date ranges. Zero values are ignored.
+	CreatedAfter  time.Time `json:"created_after,omitzero"`
+	CreatedBefore time.Time `json:"created_before,omitzero"`
+	SentAfter     time.Time `json:"sent_after,omitzero"`
+	SentBefore    time.Time `json:"sent_before,omitzero"`
+}
+
+// WaitOptions configures EmailsService.WaitForStatus.
+type WaitOptions struct {
+	// Statuses to wait for. Defaults to the terminal statuses.
+	Statuses []EmailStatus
+	// Interval is the initial delay between polls. Defaults to 1s.
+	Interval time.Duration
+	// MaxInterval caps the exponential backoff between polls. Defaults to 30s.
+	MaxInterval time.Duration
+}
+
+// StatusTransition records a status change observed while waiting.
+type StatusTransition struct {
+	// From is empty for the first observed status.
+	From       EmailStatus
+	To         EmailStatus
+	ObservedAt time.Time
+}
+
+// WaitResult represents the outcome of EmailsService.WaitForStatus.
+type WaitResult struct {
+	// Email is the most recently fetched email, or nil if none was fetched.
+	Email       *EmailDetail
+	Transitions []StatusTransition
 }
 
 // TemplateVariable represents a typed variable in a template.
@@ -161,8 +349,8 @@ type Template struct {
 	Subject    string                 `json:"subject"`
 	Variables  []TemplateVariable     `json:"variables"`
 	Status     TemplateStatus         `json:"status"`
-	CreatedAt  string                 `json:"createdAt"`
-	UpdatedAt  string                 `json:"updatedAt"`
+	CreatedAt  Time                   `json:"createdAt"`
+	UpdatedAt  Time                   `json:"updatedAt"`
 	HTML       string                 `json:"html,omitempty"`
 	Text       string                 `json:"text,omitempty"`
 	Domain     map[string]interface{} `json:"domain,omitempty"`
@@ -213,10 +401,10 @@ type Domain struct {
 	ID            string       `json:"id"`
 	Name          string       `json:"name"`
 	Status        DomainStatus `json:"status"`
-	CreatedAt     string       `json:"created_at"`
-	VerifiedAt    string       `json:"verified_at,omitempty"`
-	LastCheckedAt string       `json:"last_checked_at,omitempty"`
-	FailingSince  string       `json:"failing_since,omitempty"`
+	CreatedAt     Time         `json:"created_at"`
+	VerifiedAt    Time         `json:"verified_at,omitzero"`
+	LastCheckedAt Time         `json:"last_checked_at,omitzero"`
+	FailingSince  Time         `json:"failing_since,omitzero"`
 }
 
 // DomainWithDNSRecords represents domain with DNS records for setup.
@@ -239,9 +427,9 @@ type APIKey struct {
 	KeyPrefix  string                 `json:"key_prefix"`
 	Mode       APIKeyMode             `json:"mode"`
 	Permission APIKeyPermission       `json:"permission"`
-	CreatedAt  string                 `json:"created_at"`
-	LastUsedAt string                 `json:"last_used_at,omitempty"`
-	ExpiresAt  string                 `json:"expires_at,omitempty"`
+	CreatedAt  Time                   `json:"created_at"`
+	LastUsedAt Time                   `json:"last_used_at,omitzero"`
+	ExpiresAt  Time                   `json:"expires_at,omitzero"`
 	Domain     map[string]interface{} `json:"domain,omitempty"`
 }
 
@@ -257,7 +445,7 @@ type CreateAPIKeyRequest struct {
 	Mode       APIKeyMode       `json:"mode,omitempty"`
 	Permission APIKeyPermission `json:"permission,omitempty"`
 	DomainID   string           `json:"domainId,omitempty"`
-	ExpiresAt  string           `json:"expiresAt,omitempty"`
+	ExpiresAt  Time             `json:"expiresAt,omitzero"`
 }
 
 // ListOptions represents options for list endpoints.
@@ -281,7 +469,7 @@ type Suppression struct {
 	Email         string            `json:"email"`
 	Reason        SuppressionReason `json:"reason"`
 	SourceEmailID string            `json:"sourceEmailId,omitempty"`
-	CreatedAt     string            `json:"createdAt"`
+	CreatedAt     Time              `json:"createdAt"`
 }
 
 // SuppressionListResponse represents the response from listing suppressions.
@@ -323,11 +511,11 @@ type Contact struct {
 	Fields         map[string]string `json:"fields,omitempty"`
 	Tags           []string          `json:"tags,omitempty"`
 	Status         ContactStatus     `json:"status"`
-	UnsubscribedAt string            `json:"unsubscribedAt,omitempty"`
-	BouncedAt      string            `json:"bouncedAt,omitempty"`
-	ComplainedAt   string            `json:"complainedAt,omitempty"`
-	CreatedAt      string            `json:"createdAt"`
-	UpdatedAt      string            `json:"updatedAt"`
+	UnsubscribedAt Time              `json:"unsubscribedAt,omitzero"`
+	BouncedAt      Time              `json:"bouncedAt,omitzero"`
+	ComplainedAt   Time              `json:"complainedAt,omitzero"`
+	CreatedAt      Time              `json:"createdAt"`
+	UpdatedAt      Time              `json:"updatedAt"`
 }
 
 // CreateContactRequest represents a request to create a contact.
@@ -353,12 +541,12 @@ type BatchContactInput struct {
 
 // BatchContactResult represents the result for a single contact in batch.
 type BatchContactResult struct {
-	Index   int      `json:"index"`
-	Status  string   `json:"status"`
-	ID      string   `json:"id,omitempty"`
-	Email   string   `json:"email,omitempty"`
-	Error   string   `json:"error,omitempty"`
-	Message string   `json:"message,omitempty"`
+	Index   int    `json:"index"`
+	Status  string `json:"status"`
+	ID      string `json:"id,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 // BatchContactResponse represents the response from batch contact creation.
@@ -370,6 +558,8 @@ type BatchContactResponse struct {
 		Updated int `json:"updated"`
 		Failed  int `json:"failed"`
 	} `json:"summary"`
+	// IdempotencyKey is the key sent with the request, if any.
+	IdempotencyKey string `json:"-"`
 }
 
 // AudienceStats represents contact statistics.
@@ -429,10 +619,12 @@ type Broadcast struct {
 	Tags        []string        `json:"tags,omitempty"`
 	Status      BroadcastStatus `json:"status"`
 	Stats       *BroadcastStats `json:"stats,omitempty"`
-	ScheduledAt string          `json:"scheduledAt,omitempty"`
-	SentAt      string          `json:"sentAt,omitempty"`
-	CreatedAt   string          `json:"createdAt"`
-	UpdatedAt   string          `json:"updatedAt"`
+	ScheduledAt Time            `json:"scheduledAt,omitzero"`
+	SentAt      Time            `json:"sentAt,omitzero"`
+	CreatedAt   Time            `json:"createdAt"`
+	UpdatedAt   Time            `json:"updatedAt"`
+	// IdempotencyKey is the key sent with Send or Schedule, if any.
+	IdempotencyKey string `json:"-"`
 }
 
 // CreateBroadcastRequest represents a request to create a broadcast.
@@ -477,7 +669,7 @@ type SendBroadcastRequest struct {
 
 // ScheduleBroadcastRequest represents a request to schedule a broadcast.
 type ScheduleBroadcastRequest struct {
-	ScheduledAt string `json:"scheduledAt"`
+	ScheduledAt Time `json:"scheduledAt"`
 	BroadcastTargeting
 }
 
@@ -512,12 +704,12 @@ type BroadcastRecipient struct {
 	ContactID   string                   `json:"contactId"`
 	Email       string                   `json:"email"`
 	Status      BroadcastRecipientStatus `json:"status"`
-	SentAt      string                   `json:"sentAt,omitempty"`
-	DeliveredAt string                   `json:"deliveredAt,omitempty"`
-	OpenedAt    string                   `json:"openedAt,omitempty"`
-	ClickedAt   string                   `json:"clickedAt,omitempty"`
-	BouncedAt   string                   `json:"bouncedAt,omitempty"`
-	FailedAt    string                   `json:"failedAt,omitempty"`
+	SentAt      Time                     `json:"sentAt,omitzero"`
+	DeliveredAt Time                     `json:"deliveredAt,omitzero"`
+	OpenedAt    Time                     `json:"openedAt,omitzero"`
+	ClickedAt   Time                     `json:"clickedAt,omitzero"`
+	BouncedAt   Time                     `json:"bouncedAt,omitzero"`
+	FailedAt    Time                     `json:"failedAt,omitzero"`
 }
 
 // ListBroadcastsOptions represents options for listing broadcasts.
@@ -538,9 +730,9 @@ type ListRecipientsOptions struct {
 
 // OpensOverTime represents opens data for a time period.
 type OpensOverTime struct {
-	Date   string `json:"date"`
-	Opens  int    `json:"opens"`
-	Unique int    `json:"unique"`
+	Date   Time `json:"date"`
+	Opens  int  `json:"opens"`
+	Unique int  `json:"unique"`
 }
 
 // LinkPerformance represents click data for a link.
@@ -0,0 +1,232 @@
+package sendpigeon
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// MaxBatchSize is the maximum number of emails accepted by SendBatch.
+const MaxBatchSize = 100
+
+// fieldErrors accumulates validation failures.
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field, rule, message string) {
+	*f = append(*f, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// nest adds the failures of a nested Validate call under prefix.
+func (f *fieldErrors) nest(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		f.add(prefix, "invalid", err.Error())
+		return
+	}
+	for _, fe := range ve.Fields {
+		if fe.Field != "" {
+			fe.Field = prefix + "." + fe.Field
+		} else {
+			fe.Field = prefix
+		}
+		*f = append(*f, fe)
+	}
+}
+
+// address checks that value is a single RFC 5322 address, optionally with a
+// display name.
+func (f *fieldErrors) address(field, value string) {
+	if _, err := mail.ParseAddress(value); err != nil {
+		f.add(field, "email", fmt.Sprintf("invalid email address %q", value))
+	}
+}
+
+// addresses checks every entry of a recipient list.
+func (f *fieldErrors) addresses(field string, values []string) {
+	for i, v := range values {
+		f.address(fmt.Sprintf("%s[%d]", field, i), v)
+	}
+}
+
+// email checks that value is a bare email address without a display name.
+func (f *fieldErrors) email(field, value string) {
+	if value == "" {
+		f.add(field, "required", "email is required")
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != strings.TrimSpace(value) {
+		f.add(field, "email", fmt.Sprintf("invalid email address %q", value))
+	}
+}
+
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: f}
+}
+
+// Validate checks the request against the API's rules without sending it.
+// It returns a *ValidationError listing every invalid field.
+func (r SendEmailRequest) Validate() error {
+	var errs fieldErrors
+
+	if len(r.To) == 0 {
+		errs.add("to", "required", "at least one recipient is required")
+	}
+	errs.addresses("to", r.To)
+	errs.addresses("cc", r.CC)
+	errs.addresses("bcc", r.BCC)
+	if r.From != "" {
+		errs.address("from", r.From)
+	}
+	if r.ReplyTo != "" {
+		if _, err := mail.ParseAddressList(r.ReplyTo); err != nil {
+			errs.add("replyTo", "email", fmt.Sprintf("invalid reply-to address %q", r.ReplyTo))
+		}
+	}
+
+	if r.TemplateID == "" {
+		if r.HTML == "" && r.Text == "" {
+			errs.add("html", "required", "html, text or templateId is required")
+		}
+		if r.Subject == "" {
+			errs.add("subject", "required", "subject is required unless templateId is set")
+		}
+	}
+
+	for i, a := range r.Attachments {
+		field := fmt.Sprintf("attachments[%d]", i)
+		if a.Filename == "" {
+			errs.add(field+".filename", "required", "filename is required")
+		}
+		if a.Content == "" && a.Path == "" {
+			errs.add(field+".content", "required", "content or path is required")
+		}
+		switch a.Disposition {
+		case "", AttachmentDispositionAttachment:
+		case AttachmentDispositionInline:
+			if a.ContentID == "" {
+				errs.add(field+".contentId", "required", "contentId is required for inline attachments")
+			}
+		default:
+			errs.add(field+".disposition", "enum", fmt.Sprintf("unknown disposition %q", a.Disposition))
+		}
+	}
+
+	return errs.err()
+}
+
+// validateBatch checks a SendBatch payload.
+func validateBatch(emails []SendEmailRequest) error {
+	var errs fieldErrors
+	if len(emails) == 0 {
+		errs.add("emails", "required", "at least one email is required")
+	}
+	if len(emails) > MaxBatchSize {
+		errs.add("emails", "max", fmt.Sprintf("at most %d emails per batch, got %d", MaxBatchSize, len(emails)))
+	}
+	for i, email := range emails {
+		errs.nest(fmt.Sprintf("emails[%d]", i), email.Validate())
+	}
+	return errs.err()
+}
+
+// Validate checks the request against the API's rules without sending it.
+func (r CreateTemplateRequest) Validate() error {
+	var errs fieldErrors
+
+	if r.TemplateID == "" {
+		errs.add("templateId", "required", "templateId is required")
+	}
+	if r.Subject == "" {
+		errs.add("subject", "required", "subject is required")
+	}
+	if r.HTML == "" && r.Text == "" {
+		errs.add("html", "required", "html or text is required")
+	}
+	for i, v := range r.Variables {
+		field := fmt.Sprintf("variables[%d]", i)
+		if v.Key == "" {
+			errs.add(field+".key", "required", "key is required")
+		}
+		switch v.Type {
+		case "", TemplateVariableTypeString, TemplateVariableTypeNumber, TemplateVariableTypeBoolean:
+		default:
+			errs.add(field+".type", "enum", fmt.Sprintf("unknown variable type %q", v.Type))
+		}
+	}
+
+	return errs.err()
+}
+
+// Validate checks the request against the API's rules without sending it.
+func (r CreateBroadcastRequest) Validate() error {
+	var errs fieldErrors
+
+	if r.Name == "" {
+		errs.add("name", "required", "name is required")
+	}
+	if r.Subject == "" {
+		errs.add("subject", "required", "subject is required")
+	}
+	if r.FromEmail != "" {
+		errs.email("fromEmail", r.FromEmail)
+	}
+	if r.ReplyTo != "" {
+		errs.email("replyTo", r.ReplyTo)
+	}
+
+	return errs.err()
+}
+
+// Validate checks the request against the API's rules without sending it.
+func (r CreateContactRequest) Validate() error {
+	var errs fieldErrors
+	errs.email("email", r.Email)
+	return errs.err()
+}
+
+// Validate checks the contact against the API's rules without sending it.
+func (r BatchContactInput) Validate() error {
+	var errs fieldErrors
+	errs.email("email", r.Email)
+	return errs.err()
+}
+
+// validateContacts checks a contact batch payload.
+func validateContacts(contacts []BatchContactInput) error {
+	var errs fieldErrors
+	if len(contacts) == 0 {
+		errs.add("contacts", "required", "at least one contact is required")
+	}
+	for i, c := range contacts {
+		errs.nest(fmt.Sprintf("contacts[%d]", i), c.Validate())
+	}
+	return errs.err()
+}
+
+// validate runs check unless client-side validation is disabled, converting
+// failures into an *Error so callers see the same shape as API rejections.
+func (c *httpClient) validate(check func() error) error {
+	if c.skipValidation {
+		return nil
+	}
+	err := check()
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return wrapError(ErrorCodeValidation, err.Error(), err)
+	}
+	return &Error{
+		Code:       ErrorCodeValidation,
+		Message:    ve.Error(),
+		Validation: ve,
+	}
+}
@@ -0,0 +1,102 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendEmailRequestValidate(t *testing.T) {
+	err := SendEmailRequest{
+		To:          []string{"user@example.com", "not-an-email"},
+		CC:          []string{"Jane <jane@example.com>"},
+		Attachments: []Attachment{{Filename: "a.pdf"}},
+	}.Validate()
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	want := map[string]string{
+		"to[1]":                  "email",
+		"html":                   "required",
+		"subject":                "required",
+		"attachments[0].content": "required",
+	}
+	if len(ve.Fields) != len(want) {
+		t.Errorf("expected %d field errors, got %v", len(want), ve.Fields)
+	}
+	for _, f := range ve.Fields {
+		if rule, ok := want[f.Field]; !ok || rule != f.Rule {
+			t.Errorf("unexpected field error: %+v", f)
+		}
+	}
+
+	valid := SendEmailRequest{To: []string{"user@example.com"}, TemplateID: "tmpl_123"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid request, got %v", err)
+	}
+}
+
+func TestSendBatchValidatesBeforeSending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach the server")
+	}))
+	defer server.Close()
+
+	emails := make([]SendEmailRequest, MaxBatchSize+1)
+	for i := range emails {
+		emails[i] = SendEmailRequest{To: []string{"user@example.com"}, Subject: "Hi", Text: "Hi"}
+	}
+	emails[3].To = nil
+
+	client := New("sk_test_xxx", &ClientOptions{BaseURL: server.URL})
+	_, err := client.SendBatch(context.Background(), emails)
+
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	var apiErr *Error
+	errors.As(err, &apiErr)
+	if apiErr.Code != ErrorCodeValidation {
+		t.Errorf("expected validation_error code, got %s", apiErr.Code)
+	}
+	fields := map[string]bool{}
+	for _, f := range apiErr.Validation.Fields {
+		fields[f.Field] = true
+	}
+	if !fields["emails"] || !fields["emails[3].to"] {
+		t.Errorf("unexpected field errors: %v", apiErr.Validation.Fields)
+	}
+}
+
+func TestContactValidate(t *testing.T) {
+	if err := (CreateContactRequest{Email: "user@example.com"}).Validate(); err != nil {
+		t.Errorf("expected valid contact, got %v", err)
+	}
+	if err := (CreateContactRequest{Email: "User <user@example.com>"}).Validate(); err == nil {
+		t.Error("expected display-name address to be rejected")
+	}
+	if err := (BatchContactInput{}).Validate(); err == nil {
+		t.Error("expected missing email to be rejected")
+	}
+}
+
+func TestCreateTemplateRequestValidate(t *testing.T) {
+	valid := CreateTemplateRequest{TemplateID: "welcome", Subject: "Hi", HTML: "<p>Hi</p>"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid template, got %v", err)
+	}
+
+	err := CreateTemplateRequest{Subject: "Hi", Text: "Hi"}.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(ve.Fields) != 1 || ve.Fields[0].Field != "templateId" || ve.Fields[0].Rule != "required" {
+		t.Errorf("unexpected field errors: %v", ve.Fields)
+	}
+}
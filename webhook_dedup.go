@@ -0,0 +1,214 @@
+package sendpigeon
+
+import (
+	linkedlist "container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const webhookDedupPruneInterval = time.Hour
+
+// WebhookDedupStore remembers webhook deliveries so a WebhookVerifier can
+// detect replays and repeated deliveries. Implementations must be safe for
+// concurrent use, and Seen must be atomic: of two concurrent calls with
+// the same key, exactly one may report it as unseen.
+type WebhookDedupStore interface {
+	// Seen records key until expiresAt and reports whether it was already
+	// recorded and had not expired.
+	Seen(ctx context.Context, key string, expiresAt time.Time) (bool, error)
+	// Forget removes key. Forgetting a missing key is not an error.
+	Forget(ctx context.Context, key string) error
+}
+
+// MemoryWebhookDedupStore is a WebhookDedupStore that keeps keys in memory
+// until they expire. Keys are lost on restart and are not shared between
+// processes.
+//
+// By default the store is unbounded, so no delivery is forgotten before
+// its TTL and memory grows with the delivery rate times the verifier's
+// DedupTTL. With a capacity, the store is an LRU that evicts the least
+// recently recorded key once full, trading replay protection for bounded
+// memory: an evicted delivery is processed again if it is redelivered or
+// replayed before its timestamp is too old. Size the capacity for the peak
+// delivery rate times DedupTTL to avoid that.
+type MemoryWebhookDedupStore struct {
+	mu       sync.Mutex
+	capacity int              // zero means unbounded
+	order    *linkedlist.List // of *dedupEntry, most recent first
+	entries  map[string]*linkedlist.Element
+	now      func() time.Time
+}
+
+type dedupEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewMemoryWebhookDedupStore returns a MemoryWebhookDedupStore holding up
+// to capacity keys. A capacity of zero means unbounded.
+func NewMemoryWebhookDedupStore(capacity int) *MemoryWebhookDedupStore {
+	return &MemoryWebhookDedupStore{
+		capacity: max(capacity, 0),
+		order:    linkedlist.New(),
+		entries:  make(map[string]*linkedlist.Element),
+		now:      time.Now,
+	}
+}
+
+// Seen implements WebhookDedupStore.
+func (s *MemoryWebhookDedupStore) Seen(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if el, ok := s.entries[key]; ok {
+		if e := el.Value.(*dedupEntry); e.expiresAt.After(now) {
+			return true, nil
+		}
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+
+	// Drop expired keys from the back, then the oldest if still full
+	for el := s.order.Back(); el != nil; el = s.order.Back() {
+		e := el.Value.(*dedupEntry)
+		if e.expiresAt.After(now) && (s.capacity == 0 || s.order.Len() < s.capacity) {
+			break
+		}
+		s.order.Remove(el)
+		delete(s.entries, e.key)
+	}
+	s.entries[key] = s.order.PushFront(&dedupEntry{key: key, expiresAt: expiresAt})
+	return false, nil
+}
+
+// Forget implements WebhookDedupStore.
+func (s *MemoryWebhookDedupStore) Forget(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of keys held, including expired keys not yet
+// evicted.
+func (s *MemoryWebhookDedupStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// FileWebhookDedupStore is a WebhookDedupStore that keeps one file per key
+// in a directory, so deliveries are remembered across restarts. Expired
+// files are removed periodically. Like FileOutboxStore, a directory must
+// only be used by one process at a time.
+type FileWebhookDedupStore struct {
+	dir       string
+	mu        sync.Mutex
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewFileWebhookDedupStore returns a FileWebhookDedupStore using dir,
+// creating it if needed.
+func NewFileWebhookDedupStore(dir string) (*FileWebhookDedupStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileWebhookDedupStore{dir: dir, now: time.Now}, nil
+}
+
+// Seen implements WebhookDedupStore.
+func (s *FileWebhookDedupStore) Seen(ctx context.Context, key string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= webhookDedupPruneInterval {
+		if err := s.prune(now); err != nil {
+			return false, err
+		}
+		s.lastPrune = now
+	}
+
+	path := s.path(key)
+	expiry, err := readDedupFile(path)
+	switch {
+	case err == nil && expiry.After(now):
+		return true, nil
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return false, err
+	}
+	return false, writeFileAtomic(path, []byte(strconv.FormatInt(expiresAt.UnixNano(), 10)))
+}
+
+// Forget implements WebhookDedupStore.
+func (s *FileWebhookDedupStore) Forget(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Prune removes expired keys.
+func (s *FileWebhookDedupStore) Prune(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prune(s.now())
+}
+
+func (s *FileWebhookDedupStore) prune(now time.Time) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(s.dir, name)
+		expiry, err := readDedupFile(path)
+		if err == nil && expiry.After(now) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path hashes key into a file name, so any key is safe to use.
+func (s *FileWebhookDedupStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// readDedupFile returns the expiry stored in a key file. A corrupt file is
+// treated as expired.
+func readDedupFile(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nanos, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, nanos), nil
+}
@@ -0,0 +1,195 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryWebhookDedupStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryWebhookDedupStore(2)
+	s.now = func() time.Time { return now }
+
+	seen := func(key string, ttl time.Duration) bool {
+		t.Helper()
+		ok, err := s.Seen(ctx, key, now.Add(ttl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if seen("a", time.Minute) {
+		t.Error("first a reported as seen")
+	}
+	if !seen("a", time.Minute) {
+		t.Error("second a not reported as seen")
+	}
+
+	// Capacity 2: adding c evicts a, the oldest
+	seen("b", time.Hour)
+	seen("c", time.Hour)
+	if s.Len() != 2 {
+		t.Errorf("Len = %d, want 2", s.Len())
+	}
+	if seen("a", time.Minute) {
+		t.Error("evicted a reported as seen")
+	}
+
+	// Expired keys are not duplicates
+	seen("d", time.Second)
+	now = now.Add(2 * time.Second)
+	if seen("d", time.Second) {
+		t.Error("expired d reported as seen")
+	}
+
+	if err := s.Forget(ctx, "d"); err != nil {
+		t.Fatal(err)
+	}
+	if seen("d", time.Second) {
+		t.Error("forgotten d reported as seen")
+	}
+}
+
+func TestMemoryWebhookDedupStoreUnbounded(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryWebhookDedupStore(0)
+	s.now = func() time.Time { return now }
+
+	for i := range 20000 {
+		if ok, err := s.Seen(ctx, strconv.Itoa(i), now.Add(time.Hour)); ok || err != nil {
+			t.Fatalf("Seen(%d) = %v, %v", i, ok, err)
+		}
+	}
+	if ok, _ := s.Seen(ctx, "0", now.Add(time.Hour)); !ok {
+		t.Error("oldest unexpired key was evicted")
+	}
+
+	// Expired keys are still dropped
+	now = now.Add(2 * time.Hour)
+	s.Seen(ctx, "new", now.Add(time.Hour))
+	if s.Len() != 1 {
+		t.Errorf("Len = %d, want 1", s.Len())
+	}
+}
+
+func TestFileWebhookDedupStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now()
+
+	s, err := NewFileWebhookDedupStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Seen(ctx, "event:evt_1", now.Add(time.Hour)); err != nil || ok {
+		t.Fatalf("first Seen = %v, %v", ok, err)
+	}
+	if _, err := s.Seen(ctx, "sig:abc", now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store over the same directory remembers the key
+	s, err = NewFileWebhookDedupStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Seen(ctx, "event:evt_1", now.Add(time.Hour)); err != nil || !ok {
+		t.Errorf("Seen after reopen = %v, %v", ok, err)
+	}
+
+	if err := s.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files after prune, want 1", len(entries))
+	}
+
+	if err := s.Forget(ctx, "event:evt_1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Seen(ctx, "event:evt_1", now.Add(time.Hour)); ok {
+		t.Error("forgotten key reported as seen")
+	}
+}
+
+func TestWebhookVerifierDuplicates(t *testing.T) {
+	ctx := context.Background()
+	v := NewWebhookVerifier(StaticSecrets{{Secret: testWebhookSecret}}, &WebhookVerifierOptions{
+		DedupStore: NewMemoryWebhookDedupStore(0),
+	})
+	timestamp := time.Now().Unix()
+	verify := func(payload string, ts int64) WebhookVerifyResult {
+		return v.Verify(ctx, []byte(payload), sign(payload, testWebhookSecret, ts), strconv.FormatInt(ts, 10))
+	}
+
+	// Replay of the same request, keyed by signature
+	payload := `{"event":"email.opened"}`
+	if r := verify(payload, timestamp); !r.Valid {
+		t.Fatalf("first delivery: %s", r.Error)
+	}
+	r := verify(payload, timestamp)
+	if r.Valid || !r.Duplicate || r.Error != "Duplicate delivery" {
+		t.Errorf("replay: valid=%v duplicate=%v error=%q", r.Valid, r.Duplicate, r.Error)
+	}
+
+	// Retry with a new timestamp, keyed by event ID
+	payload = `{"id":"evt_1","event":"email.delivered"}`
+	first := verify(payload, timestamp)
+	if !first.Valid || first.DedupKey != "event:evt_1" {
+		t.Fatalf("first delivery: valid=%v key=%q", first.Valid, first.DedupKey)
+	}
+	if r := verify(payload, timestamp+1); !r.Duplicate {
+		t.Error("retry not reported as duplicate")
+	}
+
+	// Released deliveries are processed again
+	if err := v.Release(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if r := verify(payload, timestamp+2); !r.Valid {
+		t.Errorf("after release: %s", r.Error)
+	}
+
+	// Forged requests are invalid, not duplicates
+	r = v.Verify(ctx, []byte(payload), "invalid", strconv.FormatInt(timestamp, 10))
+	if r.Duplicate || r.Error != "Invalid signature" {
+		t.Errorf("forged: duplicate=%v error=%q", r.Duplicate, r.Error)
+	}
+}
+
+func TestWebhookHandlerDedup(t *testing.T) {
+	calls := 0
+	fail := true
+	h := NewWebhookHandler(testWebhookSecret, &WebhookHandlerOptions{
+		DedupStore: NewMemoryWebhookDedupStore(0),
+	}).OnDelivered(func(ctx context.Context, e *DeliveredEvent) error {
+		calls++
+		if fail {
+			fail = false
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	payload := `{"id":"evt_1","event":"email.delivered"}`
+	timestamp := time.Now().Unix()
+	for i, want := range []int{http.StatusInternalServerError, http.StatusNoContent, http.StatusOK} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, webhookRequest(payload, timestamp+int64(i)))
+		if w.Code != want {
+			t.Errorf("delivery %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
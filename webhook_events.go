@@ -0,0 +1,209 @@
+package sendpigeon
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookEvent is a typed webhook event. It is one of *DeliveredEvent,
+// *BouncedEvent, *ComplainedEvent, *OpenedEvent, *ClickedEvent, *TestEvent
+// or, for event types this version does not know, *UnknownEvent.
+//
+// Example:
+//
+//	event, err := sendpigeon.ParseWebhookEvent(body)
+//	if err != nil {
+//	    return err
+//	}
+//	switch e := event.(type) {
+//	case *sendpigeon.DeliveredEvent:
+//	    markDelivered(e.EmailID, e.Timestamp)
+//	case *sendpigeon.BouncedEvent:
+//	    if e.BounceType == sendpigeon.BounceTypeHard {
+//	        suppress(e.ToAddress, e.DiagnosticCode)
+//	    }
+//	case *sendpigeon.ClickedEvent:
+//	    recordClick(e.EmailID, e.URL)
+//	}
+type WebhookEvent interface {
+	// Type returns the event type, e.g. WebhookEventDelivered.
+	Type() string
+	// Info returns the fields shared by every event.
+	Info() WebhookEventInfo
+
+	isWebhookEvent()
+}
+
+// WebhookEventInfo holds the fields shared by every webhook event.
+type WebhookEventInfo struct {
+	// ID identifies the event. Retries of a delivery share it.
+	ID          string
+	Timestamp   time.Time
+	EmailID     string
+	ToAddress   string
+	FromAddress string
+	Subject     string
+}
+
+// Info implements WebhookEvent.
+func (i WebhookEventInfo) Info() WebhookEventInfo { return i }
+
+func (WebhookEventInfo) isWebhookEvent() {}
+
+// BounceType classifies a bounce.
+type BounceType string
+
+const (
+	// BounceTypeHard is a permanent failure, such as a mailbox that does
+	// not exist. The address should not be sent to again.
+	BounceTypeHard BounceType = "hard"
+	// BounceTypeSoft is a temporary failure, such as a full mailbox.
+	BounceTypeSoft BounceType = "soft"
+	// BounceTypeUndetermined is a bounce the provider could not classify.
+	BounceTypeUndetermined BounceType = "undetermined"
+)
+
+// parseBounceType maps the bounce types reported by the API, including
+// the Permanent/Transient naming, to a BounceType.
+func parseBounceType(s string) BounceType {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "hard", "permanent":
+		return BounceTypeHard
+	case "soft", "transient":
+		return BounceTypeSoft
+	}
+	return BounceTypeUndetermined
+}
+
+// DeliveredEvent is sent when the recipient's server accepted an email.
+type DeliveredEvent struct {
+	WebhookEventInfo
+}
+
+// BouncedEvent is sent when an email bounced.
+type BouncedEvent struct {
+	WebhookEventInfo
+	BounceType BounceType
+	// RawBounceType is the bounce type as reported by the API.
+	RawBounceType string
+	// DiagnosticCode is the receiving server's response, e.g.
+	// "smtp; 550 5.1.1 user unknown".
+	DiagnosticCode string
+}
+
+// ComplainedEvent is sent when the recipient marked an email as spam.
+type ComplainedEvent struct {
+	WebhookEventInfo
+	// ComplaintType is the feedback type reported by the mailbox
+	// provider, e.g. "abuse".
+	ComplaintType string
+}
+
+// OpenedEvent is sent when the recipient opened an email.
+type OpenedEvent struct {
+	WebhookEventInfo
+	OpenedAt time.Time
+}
+
+// ClickedEvent is sent when the recipient clicked a tracked link.
+type ClickedEvent struct {
+	WebhookEventInfo
+	ClickedAt time.Time
+	URL       string
+	// LinkIndex is the link's position in the email, or -1 if unknown.
+	LinkIndex int
+}
+
+// TestEvent is sent from the dashboard to test a webhook endpoint.
+type TestEvent struct {
+	WebhookEventInfo
+}
+
+// UnknownEvent is an event type this version of the SDK does not know.
+type UnknownEvent struct {
+	WebhookEventInfo
+	EventType string
+	Data      WebhookPayloadData
+}
+
+// Type implements WebhookEvent.
+func (*DeliveredEvent) Type() string { return WebhookEventDelivered }
+
+// Type implements WebhookEvent.
+func (*BouncedEvent) Type() string { return WebhookEventBounced }
+
+// Type implements WebhookEvent.
+func (*ComplainedEvent) Type() string { return WebhookEventComplained }
+
+// Type implements WebhookEvent.
+func (*OpenedEvent) Type() string { return WebhookEventOpened }
+
+// Type implements WebhookEvent.
+func (*ClickedEvent) Type() string { return WebhookEventClicked }
+
+// Type implements WebhookEvent.
+func (*TestEvent) Type() string { return WebhookEventTest }
+
+// Type implements WebhookEvent.
+func (e *UnknownEvent) Type() string { return e.EventType }
+
+// ParseWebhookEvent parses a raw payload into a typed WebhookEvent. Verify
+// the payload first with VerifyWebhook or a WebhookVerifier.
+func ParseWebhookEvent(payload []byte) (WebhookEvent, error) {
+	p, err := ParseWebhookPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return p.TypedEvent(), nil
+}
+
+// TypedEvent converts p into its typed WebhookEvent.
+func (p *WebhookPayload) TypedEvent() WebhookEvent {
+	d := p.Data
+	info := WebhookEventInfo{
+		ID:          p.ID,
+		Timestamp:   p.Timestamp.Time,
+		EmailID:     d.EmailID,
+		ToAddress:   d.ToAddress,
+		FromAddress: d.FromAddress,
+		Subject:     d.Subject,
+	}
+
+	switch p.Event {
+	case WebhookEventDelivered:
+		return &DeliveredEvent{WebhookEventInfo: info}
+	case WebhookEventBounced:
+		return &BouncedEvent{
+			WebhookEventInfo: info,
+			BounceType:       parseBounceType(d.BounceType),
+			RawBounceType:    d.BounceType,
+			DiagnosticCode:   d.DiagnosticCode,
+		}
+	case WebhookEventComplained:
+		return &ComplainedEvent{WebhookEventInfo: info, ComplaintType: d.ComplaintType}
+	case WebhookEventOpened:
+		return &OpenedEvent{WebhookEventInfo: info, OpenedAt: orTime(d.OpenedAt, p.Timestamp).Time}
+	case WebhookEventClicked:
+		e := &ClickedEvent{
+			WebhookEventInfo: info,
+			ClickedAt:        orTime(d.ClickedAt, p.Timestamp).Time,
+			URL:              d.LinkURL,
+			LinkIndex:        -1,
+		}
+		if d.LinkIndex != nil {
+			e.LinkIndex = *d.LinkIndex
+		}
+		return e
+	case WebhookEventTest:
+		return &TestEvent{WebhookEventInfo: info}
+	}
+	return &UnknownEvent{WebhookEventInfo: info, EventType: p.Event, Data: d}
+}
+
+// orTime returns t, or fallback if t is zero.
+func orTime(t, fallback Time) Time {
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
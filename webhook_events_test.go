@@ -0,0 +1,98 @@
+package sendpigeon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWebhookEvent(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, event WebhookEvent)
+	}{
+		{"delivered", `{"id":"evt_1","event":"email.delivered","timestamp":"2024-05-01T12:00:00Z","data":{"emailId":"em_1","toAddress":"a@example.com"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				e, ok := event.(*DeliveredEvent)
+				if !ok {
+					t.Fatalf("got %T", event)
+				}
+				if e.ID != "evt_1" || e.EmailID != "em_1" || e.ToAddress != "a@example.com" || !e.Timestamp.Equal(ts) {
+					t.Errorf("unexpected event %+v", e)
+				}
+			}},
+		{"hard bounce", `{"event":"email.bounced","data":{"bounceType":"Permanent","diagnosticCode":"smtp; 550 5.1.1 user unknown"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				e, ok := event.(*BouncedEvent)
+				if !ok {
+					t.Fatalf("got %T", event)
+				}
+				if e.BounceType != BounceTypeHard || e.RawBounceType != "Permanent" || e.DiagnosticCode != "smtp; 550 5.1.1 user unknown" {
+					t.Errorf("unexpected event %+v", e)
+				}
+			}},
+		{"soft bounce", `{"event":"email.bounced","data":{"bounceType":"soft"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				if e := event.(*BouncedEvent); e.BounceType != BounceTypeSoft {
+					t.Errorf("BounceType = %q", e.BounceType)
+				}
+			}},
+		{"unclassified bounce", `{"event":"email.bounced","data":{}}`,
+			func(t *testing.T, event WebhookEvent) {
+				if e := event.(*BouncedEvent); e.BounceType != BounceTypeUndetermined {
+					t.Errorf("BounceType = %q", e.BounceType)
+				}
+			}},
+		{"complained", `{"event":"email.complained","data":{"complaintType":"abuse"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				if e, ok := event.(*ComplainedEvent); !ok || e.ComplaintType != "abuse" {
+					t.Errorf("got %#v", event)
+				}
+			}},
+		{"opened", `{"event":"email.opened","timestamp":"2024-05-01T12:00:05Z","data":{"openedAt":"2024-05-01T12:00:00Z"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				if e, ok := event.(*OpenedEvent); !ok || !e.OpenedAt.Equal(ts) {
+					t.Errorf("got %#v", event)
+				}
+			}},
+		{"clicked", `{"event":"email.clicked","timestamp":"2024-05-01T12:00:00Z","data":{"linkUrl":"https://example.com","linkIndex":2}}`,
+			func(t *testing.T, event WebhookEvent) {
+				e, ok := event.(*ClickedEvent)
+				if !ok {
+					t.Fatalf("got %T", event)
+				}
+				// ClickedAt falls back to the event timestamp
+				if e.URL != "https://example.com" || e.LinkIndex != 2 || !e.ClickedAt.Equal(ts) {
+					t.Errorf("unexpected event %+v", e)
+				}
+			}},
+		{"legacy type field", `{"type":"webhook.test"}`,
+			func(t *testing.T, event WebhookEvent) {
+				if _, ok := event.(*TestEvent); !ok {
+					t.Errorf("got %T", event)
+				}
+			}},
+		{"unknown", `{"event":"email.deferred","data":{"emailId":"em_2"}}`,
+			func(t *testing.T, event WebhookEvent) {
+				e, ok := event.(*UnknownEvent)
+				if !ok || e.Type() != "email.deferred" || e.Info().EmailID != "em_2" {
+					t.Errorf("got %#v", event)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseWebhookEvent([]byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, event)
+		})
+	}
+
+	if _, err := ParseWebhookEvent([]byte(`not json`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
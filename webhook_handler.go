@@ -0,0 +1,225 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook request headers.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+)
+
+const defaultWebhookMaxBodySize = 1 << 20
+
+// WebhookHandlerFunc handles one verified webhook event. Returning an
+// error makes the handler respond with 500 so SendPigeon retries the
+// delivery.
+type WebhookHandlerFunc func(ctx context.Context, event WebhookEvent) error
+
+// WebhookHandlerOptions configures a WebhookHandler.
+type WebhookHandlerOptions struct {
+	// MaxAge rejects deliveries whose timestamp is older than this.
+	// Defaults to 5 minutes.
+	MaxAge time.Duration
+	// MaxBodySize rejects larger request bodies with 413. Defaults to 1 MB.
+	MaxBodySize int64
+	// Secrets replaces the secret passed to NewWebhookHandler, so several
+	// secrets can be accepted while one is rotated.
+	Secrets SecretProvider
+	// DedupStore, if set, is used to acknowledge repeated deliveries with
+	// 200 without calling any handler. See WebhookVerifierOptions.
+	DedupStore WebhookDedupStore
+	// DedupTTL is how long deliveries are remembered. Defaults to 24 hours.
+	DedupTTL time.Duration
+	// OnError is called when a registered handler returns an error.
+	OnError func(r *http.Request, err error)
+}
+
+// WebhookHandler is an http.Handler that verifies SendPigeon webhooks and
+// routes each event to the function registered for its type.
+//
+// Responses tell SendPigeon whether to retry: 2xx when the event was
+// handled, has no handler or is a duplicate, 4xx when the request is not
+// a valid delivery and must not be retried, and 500 when a handler failed.
+//
+// Example:
+//
+//	handler := sendpigeon.NewWebhookHandler("whsec_xxx", nil).
+//	    OnDelivered(func(ctx context.Context, e *sendpigeon.DeliveredEvent) error {
+//	        return markDelivered(ctx, e.EmailID, e.Timestamp)
+//	    }).
+//	    OnBounced(func(ctx context.Context, e *sendpigeon.BouncedEvent) error {
+//	        if e.BounceType != sendpigeon.BounceTypeHard {
+//	            return nil
+//	        }
+//	        return suppress(ctx, e.ToAddress, e.DiagnosticCode)
+//	    })
+//	http.Handle("/webhooks/sendpigeon", handler)
+type WebhookHandler struct {
+	verifier    *WebhookVerifier
+	maxBodySize int64
+	onError     func(*http.Request, error)
+	handlers    map[string]WebhookHandlerFunc
+	fallback    WebhookHandlerFunc
+}
+
+// NewWebhookHandler returns a WebhookHandler that verifies deliveries
+// with secret, or with WebhookHandlerOptions.Secrets if set. Without a
+// non-empty secret the handler is unconfigured and answers every delivery
+// with 500, so nothing is accepted until a secret is provided.
+func NewWebhookHandler(secret string, opts *WebhookHandlerOptions) *WebhookHandler {
+	h := &WebhookHandler{
+		maxBodySize: defaultWebhookMaxBodySize,
+		handlers:    make(map[string]WebhookHandlerFunc),
+	}
+	var secrets SecretProvider = StaticSecrets{}
+	if secret != "" {
+		secrets = StaticSecrets{{Secret: secret}}
+	}
+	var verifierOpts WebhookVerifierOptions
+	if opts != nil {
+		if opts.MaxBodySize > 0 {
+			h.maxBodySize = opts.MaxBodySize
+		}
+		if opts.Secrets != nil {
+			secrets = opts.Secrets
+		}
+		verifierOpts = WebhookVerifierOptions{
+			MaxAge:     opts.MaxAge,
+			DedupStore: opts.DedupStore,
+			DedupTTL:   opts.DedupTTL,
+		}
+		h.onError = opts.OnError
+	}
+	h.verifier = NewWebhookVerifier(secrets, &verifierOpts)
+	return h
+}
+
+// On registers fn for an event type such as WebhookEventDelivered.
+func (h *WebhookHandler) On(event string, fn WebhookHandlerFunc) *WebhookHandler {
+	h.handlers[event] = fn
+	return h
+}
+
+// OnDelivered registers fn for email.delivered events.
+func (h *WebhookHandler) OnDelivered(fn func(context.Context, *DeliveredEvent) error) *WebhookHandler {
+	return h.On(WebhookEventDelivered, typedHandler(fn))
+}
+
+// OnBounced registers fn for email.bounced events.
+func (h *WebhookHandler) OnBounced(fn func(context.Context, *BouncedEvent) error) *WebhookHandler {
+	return h.On(WebhookEventBounced, typedHandler(fn))
+}
+
+// OnComplained registers fn for email.complained events.
+func (h *WebhookHandler) OnComplained(fn func(context.Context, *ComplainedEvent) error) *WebhookHandler {
+	return h.On(WebhookEventComplained, typedHandler(fn))
+}
+
+// OnOpened registers fn for email.opened events.
+func (h *WebhookHandler) OnOpened(fn func(context.Context, *OpenedEvent) error) *WebhookHandler {
+	return h.On(WebhookEventOpened, typedHandler(fn))
+}
+
+// OnClicked registers fn for email.clicked events.
+func (h *WebhookHandler) OnClicked(fn func(context.Context, *ClickedEvent) error) *WebhookHandler {
+	return h.On(WebhookEventClicked, typedHandler(fn))
+}
+
+// OnTest registers fn for webhook.test events sent from the dashboard.
+func (h *WebhookHandler) OnTest(fn func(context.Context, *TestEvent) error) *WebhookHandler {
+	return h.On(WebhookEventTest, typedHandler(fn))
+}
+
+// typedHandler adapts a handler for one event type to WebhookHandlerFunc.
+// Events that are not of type E are ignored.
+func typedHandler[E WebhookEvent](fn func(context.Context, E) error) WebhookHandlerFunc {
+	return func(ctx context.Context, event WebhookEvent) error {
+		e, ok := event.(E)
+		if !ok {
+			return nil
+		}
+		return fn(ctx, e)
+	}
+}
+
+// Fallback registers fn for events without a specific handler. Without a
+// fallback, such events are acknowledged and ignored.
+func (h *WebhookHandler) Fallback(fn WebhookHandlerFunc) *WebhookHandler {
+	h.fallback = fn
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	result := h.verifier.Verify(r.Context(), body, r.Header.Get(WebhookSignatureHeader),
+		r.Header.Get(WebhookTimestampHeader))
+	if result.Duplicate {
+		// Already handled: acknowledge so the sender stops retrying
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !result.Valid {
+		http.Error(w, result.Error, webhookErrorStatus(result.Error))
+		return
+	}
+
+	if result.Event == nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	fn := h.handlers[result.Event.Type()]
+	if fn == nil {
+		fn = h.fallback
+	}
+	if fn != nil {
+		if err := fn(r.Context(), result.Event); err != nil {
+			// Forget the delivery so its retry is handled
+			if releaseErr := h.verifier.Release(context.WithoutCancel(r.Context()), result); releaseErr != nil {
+				err = errors.Join(err, releaseErr)
+			}
+			if h.onError != nil {
+				h.onError(r, err)
+			}
+			http.Error(w, "webhook handler failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookErrorStatus maps a verification error to a response status.
+// Secret lookup and dedup store failures are ours, so they are answered
+// with 500 to have the delivery retried.
+func webhookErrorStatus(msg string) int {
+	switch msg {
+	case webhookErrInvalidJSON:
+		return http.StatusBadRequest
+	case webhookErrSecretLookup, webhookErrNoSecrets, webhookErrDedupStore:
+		return http.StatusInternalServerError
+	}
+	return http.StatusUnauthorized
+}
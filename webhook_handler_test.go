@@ -0,0 +1,173 @@
+package sendpigeon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test123"
+
+func webhookRequest(payload string, timestamp int64) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(payload))
+	r.Header.Set(WebhookSignatureHeader, sign(payload, testWebhookSecret, timestamp))
+	r.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	return r
+}
+
+func TestWebhookHandlerRoutesEvents(t *testing.T) {
+	var got []string
+	h := NewWebhookHandler(testWebhookSecret, nil).
+		OnDelivered(func(ctx context.Context, e *DeliveredEvent) error {
+			got = append(got, e.Type()+":"+e.EmailID)
+			return nil
+		}).
+		OnBounced(func(ctx context.Context, e *BouncedEvent) error {
+			got = append(got, e.Type()+":"+e.EmailID+":"+string(e.BounceType))
+			return nil
+		})
+
+	for _, payload := range []string{
+		`{"event":"email.delivered","data":{"emailId":"e1"}}`,
+		`{"event":"email.bounced","data":{"emailId":"e2","bounceType":"Permanent"}}`,
+		`{"type":"email.delivered","data":{"emailId":"e3"}}`,
+		`{"event":"email.opened","data":{"emailId":"e4"}}`,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, webhookRequest(payload, time.Now().Unix()))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: status = %d, want 204", payload, w.Code)
+		}
+	}
+
+	want := []string{"email.delivered:e1", "email.bounced:e2:hard", "email.delivered:e3"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("handled %v, want %v", got, want)
+	}
+}
+
+func TestWebhookHandlerFallback(t *testing.T) {
+	var event string
+	h := NewWebhookHandler(testWebhookSecret, nil).
+		OnDelivered(func(ctx context.Context, e *DeliveredEvent) error { return nil }).
+		Fallback(func(ctx context.Context, e WebhookEvent) error {
+			event = e.Type()
+			return nil
+		})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, webhookRequest(`{"event":"email.clicked"}`, time.Now().Unix()))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", w.Code)
+	}
+	if event != WebhookEventClicked {
+		t.Errorf("fallback got %q", event)
+	}
+}
+
+func TestWebhookHandlerStatuses(t *testing.T) {
+	h := NewWebhookHandler(testWebhookSecret, &WebhookHandlerOptions{MaxBodySize: 64}).
+		OnDelivered(func(ctx context.Context, e *DeliveredEvent) error {
+			return errors.New("database unavailable")
+		})
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"method", func() *http.Request { return httptest.NewRequest(http.MethodGet, "/webhooks", nil) }, http.StatusMethodNotAllowed},
+		{"signature", func() *http.Request {
+			r := webhookRequest(`{"event":"email.bounced"}`, now)
+			r.Header.Set(WebhookSignatureHeader, "invalid")
+			return r
+		}, http.StatusUnauthorized},
+		{"expired", func() *http.Request { return webhookRequest(`{"event":"email.bounced"}`, now-600) }, http.StatusUnauthorized},
+		{"json", func() *http.Request { return webhookRequest(`not json`, now) }, http.StatusBadRequest},
+		{"too large", func() *http.Request {
+			return webhookRequest(`{"event":"email.bounced","pad":"`+strings.Repeat("x", 100)+`"}`, now)
+		}, http.StatusRequestEntityTooLarge},
+		{"handler error", func() *http.Request { return webhookRequest(`{"event":"email.delivered"}`, now) }, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.req())
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerContext(t *testing.T) {
+	type ctxKey struct{}
+	var got interface{}
+	var handlerErr error
+	h := NewWebhookHandler(testWebhookSecret, &WebhookHandlerOptions{
+		OnError: func(r *http.Request, err error) { handlerErr = err },
+	}).OnTest(func(ctx context.Context, e *TestEvent) error {
+		got = ctx.Value(ctxKey{})
+		return errors.New("boom")
+	})
+
+	r := webhookRequest(`{"event":"webhook.test"}`, time.Now().Unix())
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "request-scoped"))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != "request-scoped" {
+		t.Errorf("handler context value = %v", got)
+	}
+	if handlerErr == nil || handlerErr.Error() != "boom" {
+		t.Errorf("OnError got %v", handlerErr)
+	}
+}
+
+func TestWebhookHandlerSecrets(t *testing.T) {
+	payload := `{"event":"email.delivered"}`
+	timestamp := time.Now().Unix()
+	h := NewWebhookHandler("", &WebhookHandlerOptions{
+		Secrets: StaticSecrets{{ID: "current", Secret: "whsec_new"}, {ID: "previous", Secret: testWebhookSecret}},
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, webhookRequest(payload, timestamp))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("previous secret: status = %d, want 204", w.Code)
+	}
+
+	h = NewWebhookHandler("", &WebhookHandlerOptions{Secrets: failingSecrets{}})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, webhookRequest(payload, timestamp))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("secret lookup failure: status = %d, want 500", w.Code)
+	}
+}
+
+func TestWebhookHandlerEmptySecret(t *testing.T) {
+	payload := `{"event":"email.delivered"}`
+	timestamp := time.Now().Unix()
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(payload))
+	r.Header.Set(WebhookSignatureHeader, sign(payload, "", timestamp))
+	r.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+
+	called := false
+	h := NewWebhookHandler("", nil).Fallback(func(ctx context.Context, e WebhookEvent) error {
+		called = true
+		return nil
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if called {
+		t.Error("handler called for a delivery signed with an empty secret")
+	}
+}
@@ -1,6 +1,7 @@
 package sendpigeon
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -8,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,24 +25,29 @@ const (
 
 // WebhookPayloadData represents the typed webhook payload data.
 type WebhookPayloadData struct {
-	EmailID       string `json:"emailId,omitempty"`
-	ToAddress     string `json:"toAddress,omitempty"`
-	FromAddress   string `json:"fromAddress,omitempty"`
-	Subject       string `json:"subject,omitempty"`
-	BounceType    string `json:"bounceType,omitempty"`
+	EmailID     string `json:"emailId,omitempty"`
+	ToAddress   string `json:"toAddress,omitempty"`
+	FromAddress string `json:"fromAddress,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	// Present for email.bounced events
+	BounceType     string `json:"bounceType,omitempty"`
+	DiagnosticCode string `json:"diagnosticCode,omitempty"`
+	// Present for email.complained events
 	ComplaintType string `json:"complaintType,omitempty"`
 	// Present for email.opened events
-	OpenedAt string `json:"openedAt,omitempty"`
+	OpenedAt Time `json:"openedAt,omitzero"`
 	// Present for email.clicked events
-	ClickedAt string `json:"clickedAt,omitempty"`
+	ClickedAt Time   `json:"clickedAt,omitzero"`
 	LinkURL   string `json:"linkUrl,omitempty"`
 	LinkIndex *int   `json:"linkIndex,omitempty"`
 }
 
 // WebhookPayload represents a typed webhook event.
 type WebhookPayload struct {
+	// ID identifies the event. Retries of a delivery share it.
+	ID        string             `json:"id,omitempty"`
 	Event     string             `json:"event"`
-	Timestamp string             `json:"timestamp"`
+	Timestamp Time               `json:"timestamp"`
 	Data      WebhookPayloadData `json:"data"`
 }
 
@@ -48,16 +55,84 @@ type WebhookPayload struct {
 type WebhookVerifyResult struct {
 	Valid   bool
 	Payload map[string]interface{}
-	Error   string
+	// Event is the typed event, or nil if the payload does not have the
+	// shape of a webhook event.
+	Event WebhookEvent
+	Error string
+	// KeyID is the ID of the WebhookSecret that matched the signature.
+	KeyID string
+	// Duplicate is set, with Valid false, when a correctly signed delivery
+	// was already seen by the verifier's WebhookDedupStore.
+	Duplicate bool
+	// DedupKey is the key the delivery was recorded under in the
+	// WebhookDedupStore, if any.
+	DedupKey string
+}
+
+// Webhook verification errors reported in WebhookVerifyResult.Error.
+const (
+	webhookErrInvalidTimestamp = "Invalid timestamp"
+	webhookErrTimestampTooOld  = "Timestamp too old"
+	webhookErrInvalidSignature = "Invalid signature"
+	webhookErrInvalidJSON      = "Invalid JSON payload"
+	webhookErrNoSecrets        = "No webhook secrets configured"
+	webhookErrSecretLookup     = "Webhook secret lookup failed"
+	webhookErrDuplicate        = "Duplicate delivery"
+	webhookErrDedupStore       = "Webhook dedup store failed"
+)
+
+const (
+	defaultWebhookMaxAge   = 5 * time.Minute
+	defaultWebhookDedupTTL = 24 * time.Hour
+)
+
+// WebhookSecret is one secret a webhook may be signed with. Empty secrets
+// are ignored, so a provider returning only empty secrets is treated as
+// having none.
+type WebhookSecret struct {
+	// ID names the secret, e.g. "current" or "previous". It is reported in
+	// WebhookVerifyResult.KeyID when the secret matches.
+	ID     string
+	Secret string
+}
+
+// SecretProvider returns the secrets webhook signatures are checked
+// against. Return every secret that is valid during a rotation, newest
+// first. Implementations backed by a secret store should cache, as
+// WebhookSecrets is called for every delivery.
+type SecretProvider interface {
+	WebhookSecrets(ctx context.Context) ([]WebhookSecret, error)
+}
+
+// StaticSecrets is a SecretProvider with a fixed set of secrets.
+//
+// Example:
+//
+//	secrets := sendpigeon.StaticSecrets{
+//	    {ID: "current", Secret: "whsec_new"},
+//	    {ID: "previous", Secret: "whsec_old"},
+//	}
+type StaticSecrets []WebhookSecret
+
+// WebhookSecrets implements SecretProvider.
+func (s StaticSecrets) WebhookSecrets(ctx context.Context) ([]WebhookSecret, error) {
+	return s, nil
 }
 
 // ParseWebhookPayload parses a raw payload into a typed WebhookPayload.
 func ParseWebhookPayload(payload []byte) (*WebhookPayload, error) {
-	var p WebhookPayload
+	var p struct {
+		WebhookPayload
+		// Older deliveries name the event "type"
+		Type string `json:"type"`
+	}
 	if err := json.Unmarshal(payload, &p); err != nil {
 		return nil, err
 	}
-	return &p, nil
+	if p.Event == "" {
+		p.Event = p.Type
+	}
+	return &p.WebhookPayload, nil
 }
 
 // VerifyWebhook verifies a webhook signature from SendPigeon.
@@ -82,43 +157,230 @@ func ParseWebhookPayload(payload []byte) (*WebhookPayload, error) {
 //	    handleEvent(result.Payload)
 //	}
 func VerifyWebhook(payload []byte, signature, timestamp, secret string, maxAge int) WebhookVerifyResult {
-	if maxAge <= 0 {
-		maxAge = 300
+	return VerifyWebhookSecrets(context.Background(), payload, signature, timestamp, StaticSecrets{{Secret: secret}}, maxAge)
+}
+
+// VerifyWebhookSecrets verifies a webhook signature against every secret
+// from secrets, so deliveries signed with either the old or the new secret
+// are accepted while a secret is rotated. The signature header may hold a
+// single hex signature or several comma-separated "v1=<hex>" entries, as
+// sent while the server signs with more than one secret. The ID of the
+// matching secret is reported in KeyID.
+//
+// Example:
+//
+//	result := sendpigeon.VerifyWebhookSecrets(
+//	    ctx,
+//	    body,
+//	    req.Header.Get("X-Webhook-Signature"),
+//	    req.Header.Get("X-Webhook-Timestamp"),
+//	    sendpigeon.StaticSecrets{
+//	        {ID: "current", Secret: "whsec_new"},
+//	        {ID: "previous", Secret: "whsec_old"},
+//	    },
+//	    300,
+//	)
+//	if result.Valid && result.KeyID == "previous" {
+//	    log.Print("webhook still signed with the previous secret")
+//	}
+func VerifyWebhookSecrets(ctx context.Context, payload []byte, signature, timestamp string, secrets SecretProvider, maxAge int) WebhookVerifyResult {
+	v := NewWebhookVerifier(secrets, &WebhookVerifierOptions{MaxAge: time.Duration(maxAge) * time.Second})
+	return v.Verify(ctx, payload, signature, timestamp)
+}
+
+// WebhookVerifierOptions configures a WebhookVerifier.
+type WebhookVerifierOptions struct {
+	// MaxAge rejects deliveries whose timestamp is further than this from
+	// now. Defaults to 5 minutes.
+	MaxAge time.Duration
+	// DedupStore, if set, records every verified delivery so replays and
+	// repeated deliveries are reported as duplicates.
+	DedupStore WebhookDedupStore
+	// DedupTTL is how long deliveries are remembered. Defaults to 24 hours
+	// and is never shorter than twice MaxAge, the window in which a
+	// replayed request would still pass the timestamp check.
+	DedupTTL time.Duration
+}
+
+// WebhookVerifier verifies webhook deliveries against a set of secrets
+// and, with a WebhookDedupStore, rejects deliveries it has already seen.
+// Deliveries are keyed by their event ID, so a retried delivery is caught
+// too, or by their signature if the payload has no ID.
+//
+// Example:
+//
+//	verifier := sendpigeon.NewWebhookVerifier(
+//	    sendpigeon.StaticSecrets{{Secret: "whsec_xxx"}},
+//	    &sendpigeon.WebhookVerifierOptions{DedupStore: sendpigeon.NewMemoryWebhookDedupStore(0)},
+//	)
+//	result := verifier.Verify(ctx, body, signature, timestamp)
+//	switch {
+//	case result.Duplicate:
+//	    w.WriteHeader(http.StatusOK) // already handled
+//	case !result.Valid:
+//	    http.Error(w, result.Error, http.StatusUnauthorized)
+//	default:
+//	    if err := handleEvent(result.Payload); err != nil {
+//	        verifier.Release(ctx, result) // let the retry through
+//	        http.Error(w, "failed", http.StatusInternalServerError)
+//	    }
+//	}
+type WebhookVerifier struct {
+	secrets  SecretProvider
+	maxAge   time.Duration
+	dedup    WebhookDedupStore
+	dedupTTL time.Duration
+}
+
+// NewWebhookVerifier returns a WebhookVerifier that checks signatures
+// against secrets.
+func NewWebhookVerifier(secrets SecretProvider, opts *WebhookVerifierOptions) *WebhookVerifier {
+	v := &WebhookVerifier{
+		secrets:  secrets,
+		maxAge:   defaultWebhookMaxAge,
+		dedupTTL: defaultWebhookDedupTTL,
 	}
+	if opts != nil {
+		if opts.MaxAge > 0 {
+			v.maxAge = opts.MaxAge
+		}
+		if opts.DedupTTL > 0 {
+			v.dedupTTL = opts.DedupTTL
+		}
+		v.dedup = opts.DedupStore
+	}
+	v.dedupTTL = max(v.dedupTTL, 2*v.maxAge)
+	return v
+}
 
+// Verify checks a delivery's timestamp and signature, parses its payload
+// and, with a WebhookDedupStore, records it. A delivery already recorded
+// is reported with Duplicate set.
+func (v *WebhookVerifier) Verify(ctx context.Context, payload []byte, signature, timestamp string) WebhookVerifyResult {
 	// Validate timestamp
 	ts, err := strconv.ParseInt(timestamp, 10, 64)
 	if err != nil {
-		return WebhookVerifyResult{Valid: false, Error: "Invalid timestamp"}
+		return WebhookVerifyResult{Valid: false, Error: webhookErrInvalidTimestamp}
 	}
 
-	now := time.Now().Unix()
-	if abs(now-ts) > int64(maxAge) {
-		return WebhookVerifyResult{Valid: false, Error: "Timestamp too old"}
+	now := time.Now()
+	if abs(now.Unix()-ts) > int64(v.maxAge/time.Second) {
+		return WebhookVerifyResult{Valid: false, Error: webhookErrTimestampTooOld}
 	}
 
-	// Compute expected signature
-	signedPayload := fmt.Sprintf("%s.%s", timestamp, string(payload))
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(signedPayload))
-	expected := hex.EncodeToString(mac.Sum(nil))
+	keys, err := v.secrets.WebhookSecrets(ctx)
+	if err != nil {
+		return WebhookVerifyResult{Valid: false, Error: webhookErrSecretLookup}
+	}
+	keys = configuredSecrets(keys)
+	if len(keys) == 0 {
+		return WebhookVerifyResult{Valid: false, Error: webhookErrNoSecrets}
+	}
 
-	// Timing-safe comparison
-	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
-		return WebhookVerifyResult{Valid: false, Error: "Invalid signature"}
+	key, sig, ok := matchSignature(payload, timestamp, parseSignatures(signature), keys)
+	if !ok {
+		return WebhookVerifyResult{Valid: false, Error: webhookErrInvalidSignature}
 	}
 
 	// Parse payload
 	var data map[string]interface{}
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return WebhookVerifyResult{Valid: false, Error: "Invalid JSON payload"}
+		return WebhookVerifyResult{Valid: false, Error: webhookErrInvalidJSON}
+	}
+
+	result := WebhookVerifyResult{Valid: true, Payload: data, KeyID: key.ID}
+	if event, err := ParseWebhookEvent(payload); err == nil {
+		result.Event = event
+	}
+	if v.dedup == nil {
+		return result
+	}
+
+	// Only record deliveries that passed verification, so forged requests
+	// cannot fill the store
+	result.DedupKey = webhookDedupKey(data, sig)
+	seen, err := v.dedup.Seen(ctx, result.DedupKey, now.Add(v.dedupTTL))
+	if err != nil {
+		return WebhookVerifyResult{Valid: false, Payload: data, KeyID: key.ID, Error: webhookErrDedupStore}
+	}
+	if seen {
+		result.Valid = false
+		result.Duplicate = true
+		result.Error = webhookErrDuplicate
+	}
+	return result
+}
+
+// Release forgets a delivery recorded by Verify, so a retry of it is
+// processed again. Call it when handling the delivery failed.
+func (v *WebhookVerifier) Release(ctx context.Context, result WebhookVerifyResult) error {
+	if v.dedup == nil || result.DedupKey == "" || result.Duplicate {
+		return nil
 	}
+	return v.dedup.Forget(ctx, result.DedupKey)
+}
+
+// webhookDedupKey keys a delivery by its event ID, or by its signature if
+// the payload has none.
+func webhookDedupKey(data map[string]interface{}, signature string) string {
+	if id, _ := data["id"].(string); id != "" {
+		return "event:" + id
+	}
+	return "sig:" + signature
+}
 
-	return WebhookVerifyResult{Valid: true, Payload: data}
+// parseSignatures splits a signature header into its candidate
+// signatures. Entries with a version other than v1 are ignored.
+func parseSignatures(header string) []string {
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if version, sig, ok := strings.Cut(part, "="); ok {
+			if strings.TrimSpace(version) != "v1" {
+				continue
+			}
+			part = strings.TrimSpace(sig)
+		}
+		if part != "" {
+			sigs = append(sigs, part)
+		}
+	}
+	return sigs
+}
+
+// configuredSecrets drops empty secrets, which anyone could sign with.
+func configuredSecrets(secrets []WebhookSecret) []WebhookSecret {
+	out := secrets[:0:0]
+	for _, s := range secrets {
+		if s.Secret != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matchSignature returns the first secret that produced one of sigs, and
+// the signature it produced. Every comparison is constant-time.
+func matchSignature(payload []byte, timestamp string, sigs []string, secrets []WebhookSecret) (WebhookSecret, string, bool) {
+	for _, secret := range secrets {
+		// Compute expected signature
+		mac := hmac.New(sha256.New, []byte(secret.Secret))
+		fmt.Fprintf(mac, "%s.", timestamp)
+		mac.Write(payload)
+		expected := hex.EncodeToString(mac.Sum(nil))
+
+		for _, sig := range sigs {
+			if subtle.ConstantTimeCompare([]byte(expected), []byte(sig)) == 1 {
+				return secret, sig, true
+			}
+		}
+	}
+	return WebhookSecret{}, "", false
 }
 
 // VerifyInboundWebhook verifies an inbound email webhook signature.
-// Same verification logic as regular webhooks.
+// Same verification logic as regular webhooks. Use ParseInboundEmail to
+// read the verified payload.
 func VerifyInboundWebhook(payload []byte, signature, timestamp, secret string, maxAge int) WebhookVerifyResult {
 	return VerifyWebhook(payload, signature, timestamp, secret, maxAge)
 }
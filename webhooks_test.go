@@ -1,9 +1,11 @@
 package sendpigeon
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -142,3 +144,79 @@ func TestVerifyInboundWebhook(t *testing.T) {
 		t.Errorf("expected valid, got error: %s", result.Error)
 	}
 }
+
+func TestVerifyWebhookSecretsRotation(t *testing.T) {
+	secrets := StaticSecrets{
+		{ID: "current", Secret: "whsec_new"},
+		{ID: "previous", Secret: "whsec_old"},
+	}
+	payload := `{"type":"email.delivered"}`
+	timestamp := time.Now().Unix()
+	ts := strconv.FormatInt(timestamp, 10)
+
+	tests := []struct {
+		name      string
+		signature string
+		keyID     string
+	}{
+		{"old secret", sign(payload, "whsec_old", timestamp), "previous"},
+		{"new secret", sign(payload, "whsec_new", timestamp), "current"},
+		{"multi signature", "v1=" + sign(payload, "whsec_other", timestamp) + ", v1=" + sign(payload, "whsec_old", timestamp), "previous"},
+		{"unknown version", "v0=abc,v1=" + sign(payload, "whsec_new", timestamp), "current"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := VerifyWebhookSecrets(context.Background(), []byte(payload), tt.signature, ts, secrets, 300)
+			if !result.Valid {
+				t.Fatalf("expected valid, got error: %s", result.Error)
+			}
+			if result.KeyID != tt.keyID {
+				t.Errorf("KeyID = %q, want %q", result.KeyID, tt.keyID)
+			}
+		})
+	}
+
+	result := VerifyWebhookSecrets(context.Background(), []byte(payload), "v1="+sign(payload, "whsec_retired", timestamp), ts, secrets, 300)
+	if result.Valid || result.Error != "Invalid signature" {
+		t.Errorf("retired secret: valid=%v error=%q", result.Valid, result.Error)
+	}
+}
+
+type failingSecrets struct{}
+
+func (failingSecrets) WebhookSecrets(ctx context.Context) ([]WebhookSecret, error) {
+	return nil, errors.New("vault unavailable")
+}
+
+func TestVerifyWebhookSecretsProviderError(t *testing.T) {
+	payload := `{"type":"email.delivered"}`
+	timestamp := time.Now().Unix()
+
+	result := VerifyWebhookSecrets(context.Background(), []byte(payload), sign(payload, "whsec_x", timestamp),
+		strconv.FormatInt(timestamp, 10), failingSecrets{}, 300)
+	if result.Valid || result.Error != webhookErrSecretLookup {
+		t.Errorf("valid=%v error=%q", result.Valid, result.Error)
+	}
+}
+
+func TestVerifyWebhookEmptySecret(t *testing.T) {
+	payload := `{"type":"email.delivered"}`
+	timestamp := time.Now().Unix()
+	ts := strconv.FormatInt(timestamp, 10)
+
+	// Anyone can compute a signature with an empty key
+	forged := sign(payload, "", timestamp)
+	if result := VerifyWebhook([]byte(payload), forged, ts, "", 300); result.Valid || result.Error != webhookErrNoSecrets {
+		t.Errorf("empty secret: valid=%v error=%q", result.Valid, result.Error)
+	}
+
+	secrets := StaticSecrets{{ID: "unset", Secret: ""}, {ID: "current", Secret: "whsec_new"}}
+	result := VerifyWebhookSecrets(context.Background(), []byte(payload), forged, ts, secrets, 300)
+	if result.Valid || result.Error != webhookErrInvalidSignature {
+		t.Errorf("forged with empty secret: valid=%v error=%q", result.Valid, result.Error)
+	}
+	result = VerifyWebhookSecrets(context.Background(), []byte(payload), sign(payload, "whsec_new", timestamp), ts, secrets, 300)
+	if !result.Valid || result.KeyID != "current" {
+		t.Errorf("valid=%v keyID=%q error=%q", result.Valid, result.KeyID, result.Error)
+	}
+}